@@ -2,23 +2,40 @@ package main
 
 import (
 	"app/internal/application"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
 )
 
 func main() {
 	// env
-	// ...
+	// - config from flags, environment variables and config file, see application.LoadConfigServerChi
+	cfg, printConfig, err := application.LoadConfigServerChi(os.Args[1:], os.LookupEnv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// - print the resulting config and exit, to debug deployments
+	if printConfig {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// app
-	// - config
-	cfg := &application.ConfigServerChi{
-		ServerAddress: ":8080",
-		LoaderFilePath: "docs/db/vehicles_100.json",
-	}
 	app := application.NewServerChi(cfg)
 	// - run
 	if err := app.Run(); err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
@@ -1,30 +1,98 @@
 package application
 
 import (
+	"app/internal"
 	"app/internal/handler"
 	"app/internal/loader"
 	"app/internal/repository"
 	"app/internal/service"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// RepositoryMap is the repository type that keeps the vehicles only in memory
+	RepositoryMap = "map"
+	// RepositoryFile is the repository type that writes every change back to the loader file
+	RepositoryFile = "file"
+	// RepositoryLog is the repository type that records every change in an append-only log on top of the loader file
+	RepositoryLog = "log"
+	// RepositorySQL is the repository type that stores the vehicles in a SQLite database, seeded from the loader file when empty
+	RepositorySQL = "sql"
+)
+
+const (
+	// RegistrationConflictsFail is the policy that refuses to start when several vehicles of the loader file share a registration
+	RegistrationConflictsFail = "fail"
+	// RegistrationConflictsKeepFirst is the policy that keeps the vehicle with the lowest id of each shared registration,
+	// the others are skipped and logged
+	RegistrationConflictsKeepFirst = "keep_first"
+)
+
+var (
+	// ErrUnknownRepository is an error that represents that the configured repository type is not supported
+	ErrUnknownRepository = errors.New("unknown repository type")
+	// ErrShuttingDown is an error that represents that the application is shutting down
+	ErrShuttingDown = errors.New("shutting down")
 )
 
 // ConfigServerChi is a struct that represents the configuration for ServerChi
+// - LoadConfigServerChi reads it from flags, environment variables and a config file
+// - unset options, empty text or a nil number or duration, take their value of DefaultConfigServerChi,
+// set ones are applied as given, so an explicit 0 disables a timeout while an omitted one keeps its default
 type ConfigServerChi struct {
 	// ServerAddress is the address where the server will be listening
-	ServerAddress string
+	ServerAddress string `json:"server_address" yaml:"server_address"`
 	// LoaderFilePath is the path to the file that contains the vehicles
-	LoaderFilePath string
+	LoaderFilePath string `json:"loader_file_path" yaml:"loader_file_path"`
+	// RepositoryType is the type of repository used to store the vehicles (RepositoryMap, RepositoryFile, RepositoryLog or RepositorySQL)
+	RepositoryType string `json:"repository_type" yaml:"repository_type"`
+	// RegistrationConflicts is the policy applied when several vehicles of the loader file share a registration
+	// (RegistrationConflictsFail or RegistrationConflictsKeepFirst)
+	RegistrationConflicts string `json:"registration_conflicts" yaml:"registration_conflicts"`
+	// LogFilePath is the path to the log file used by RepositoryLog, by default the loader file path with a .log suffix
+	LogFilePath string `json:"log_file_path" yaml:"log_file_path"`
+	// LogCompactEvery is the number of log records after which RepositoryLog writes a new snapshot, 0 or negative disables compaction
+	LogCompactEvery *int `json:"log_compact_every" yaml:"log_compact_every"`
+	// SQLDataSource is the SQLite data source used by RepositorySQL
+	SQLDataSource string `json:"sql_data_source" yaml:"sql_data_source"`
+	// ReadTimeout is the maximum duration for reading a request, headers and body, 0 for none
+	ReadTimeout *Duration `json:"read_timeout" yaml:"read_timeout"`
+	// WriteTimeout is the maximum duration for writing a response, counted from the end of the request headers, 0 for none
+	WriteTimeout *Duration `json:"write_timeout" yaml:"write_timeout"`
+	// IdleTimeout is the maximum duration a keep-alive connection waits for the next request, 0 to use ReadTimeout
+	IdleTimeout *Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	// DrainDelay is the duration the readiness probe fails on shutdown before the server stops accepting connections,
+	// so that load balancers stop routing to it first, 0 for none
+	DrainDelay *Duration `json:"drain_delay" yaml:"drain_delay"`
+	// ShutdownTimeout is the maximum duration in-flight requests are drained for on SIGINT or SIGTERM, 0 to wait for all of them
+	ShutdownTimeout *Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// RequestTimeout is the maximum duration the service and the repository spend on a request,
+	// past it the request fails with 504 Gateway Timeout, 0 for none
+	RequestTimeout *Duration `json:"request_timeout" yaml:"request_timeout"`
 }
 
 // NewServerChi is a function that returns a new instance of ServerChi
 func NewServerChi(cfg *ConfigServerChi) *ServerChi {
 	// default values
-	defaultConfig := &ConfigServerChi{
-		ServerAddress: ":8080",
-	}
+	defaultConfig := DefaultConfigServerChi()
 	if cfg != nil {
 		if cfg.ServerAddress != "" {
 			defaultConfig.ServerAddress = cfg.ServerAddress
@@ -32,43 +100,326 @@ func NewServerChi(cfg *ConfigServerChi) *ServerChi {
 		if cfg.LoaderFilePath != "" {
 			defaultConfig.LoaderFilePath = cfg.LoaderFilePath
 		}
+		if cfg.RepositoryType != "" {
+			defaultConfig.RepositoryType = cfg.RepositoryType
+		}
+		if cfg.RegistrationConflicts != "" {
+			defaultConfig.RegistrationConflicts = cfg.RegistrationConflicts
+		}
+		if cfg.LogFilePath != "" {
+			defaultConfig.LogFilePath = cfg.LogFilePath
+		}
+		if cfg.LogCompactEvery != nil {
+			defaultConfig.LogCompactEvery = cfg.LogCompactEvery
+		}
+		if cfg.SQLDataSource != "" {
+			defaultConfig.SQLDataSource = cfg.SQLDataSource
+		}
+		if cfg.ReadTimeout != nil {
+			defaultConfig.ReadTimeout = cfg.ReadTimeout
+		}
+		if cfg.WriteTimeout != nil {
+			defaultConfig.WriteTimeout = cfg.WriteTimeout
+		}
+		if cfg.IdleTimeout != nil {
+			defaultConfig.IdleTimeout = cfg.IdleTimeout
+		}
+		if cfg.DrainDelay != nil {
+			defaultConfig.DrainDelay = cfg.DrainDelay
+		}
+		if cfg.ShutdownTimeout != nil {
+			defaultConfig.ShutdownTimeout = cfg.ShutdownTimeout
+		}
+		if cfg.RequestTimeout != nil {
+			defaultConfig.RequestTimeout = cfg.RequestTimeout
+		}
+	}
+	if defaultConfig.LogFilePath == "" {
+		defaultConfig.LogFilePath = defaultConfig.LoaderFilePath + ".log"
 	}
 
 	return &ServerChi{
-		serverAddress:  defaultConfig.ServerAddress,
-		loaderFilePath: defaultConfig.LoaderFilePath,
+		loaderFilePath:        defaultConfig.LoaderFilePath,
+		repositoryType:        defaultConfig.RepositoryType,
+		registrationConflicts: defaultConfig.RegistrationConflicts,
+		logFilePath:           defaultConfig.LogFilePath,
+		logCompactEvery:       *defaultConfig.LogCompactEvery,
+		sqlDataSource:         defaultConfig.SQLDataSource,
+		server: &http.Server{
+			Addr:              defaultConfig.ServerAddress,
+			ReadTimeout:       time.Duration(*defaultConfig.ReadTimeout),
+			ReadHeaderTimeout: time.Duration(*defaultConfig.ReadTimeout),
+			WriteTimeout:      time.Duration(*defaultConfig.WriteTimeout),
+			IdleTimeout:       time.Duration(*defaultConfig.IdleTimeout),
+		},
+		drainDelay:      time.Duration(*defaultConfig.DrainDelay),
+		shutdownTimeout: time.Duration(*defaultConfig.ShutdownTimeout),
+		requestTimeout:  time.Duration(*defaultConfig.RequestTimeout),
+	}
+}
+
+// requestTimeout is a function that returns a middleware that cancels the context of each request after d
+// - handlers pass the context down to the service and the repository, which give up once it is done
+// - d of 0 or less leaves the requests without deadline
+func requestTimeout(d time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
 }
 
 // ServerChi is a struct that implements the Application interface
 type ServerChi struct {
-	// serverAddress is the address where the server will be listening
-	serverAddress string
 	// loaderFilePath is the path to the file that contains the vehicles
 	loaderFilePath string
+	// repositoryType is the type of repository used to store the vehicles
+	repositoryType string
+	// registrationConflicts is the policy applied when several vehicles of the loader file share a registration
+	registrationConflicts string
+	// logFilePath is the path to the log file used by RepositoryLog
+	logFilePath string
+	// logCompactEvery is the number of log records after which RepositoryLog writes a new snapshot
+	logCompactEvery int
+	// sqlDataSource is the SQLite data source used by RepositorySQL
+	sqlDataSource string
+	// server is the HTTP server, created up front so that Shutdown can be called at any time
+	server *http.Server
+	// drainDelay is the duration the readiness probe fails on shutdown before the server stops accepting connections
+	drainDelay time.Duration
+	// shutdownTimeout is the maximum duration in-flight requests are drained for on SIGINT or SIGTERM
+	shutdownTimeout time.Duration
+	// requestTimeout is the maximum duration the service and the repository spend on a request
+	requestTimeout time.Duration
+	// draining is set when Shutdown starts, so that the readiness probe fails while in-flight requests finish
+	draining atomic.Bool
+	// mu guards closers
+	mu sync.Mutex
+	// closers is the list of resources released by Shutdown, in reverse order, once no request uses them
+	closers []func() error
+	// shutdownOnce makes Shutdown run once
+	shutdownOnce sync.Once
+	// shutdownErr is the error of the first Shutdown
+	shutdownErr error
 }
 
-// Run is a method that runs the application
+// Run is a method that runs the application until it receives SIGINT or SIGTERM, or Shutdown is called
+// - on a signal, the readiness probe fails for the drain delay, in-flight requests are drained for at most the shutdown timeout,
+// then the repository is closed
 func (a *ServerChi) Run() (err error) {
+	a.server.Handler, err = a.handler()
+	if err != nil {
+		err = errors.Join(err, a.close())
+		return
+	}
+
+	// serve until a signal arrives or Shutdown is called
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := make(chan error, 1)
+	go func() {
+		served <- a.server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-served:
+		if !errors.Is(err, http.ErrServerClosed) {
+			// the server could not start, nothing was routed to it so there is nothing to drain
+			err = errors.Join(err, a.shutdown(context.Background(), 0))
+			return
+		}
+		// Shutdown was called, wait for it to finish
+		err = a.Shutdown(context.Background())
+	case <-ctx.Done():
+		// a second signal terminates the process right away
+		stop()
+		shutdownCtx, cancel := context.WithCancel(context.Background())
+		if a.shutdownTimeout > 0 {
+			shutdownCtx, cancel = context.WithTimeout(context.Background(), a.drainDelay+a.shutdownTimeout)
+		}
+		defer cancel()
+		err = a.Shutdown(shutdownCtx)
+	}
+	return
+}
+
+// Shutdown is a method that stops the application gracefully
+// - the readiness probe fails right away and the server keeps serving for the drain delay,
+// so that load balancers stop routing new requests to it
+// - then the server stops accepting connections and waits for the in-flight requests until ctx is done,
+// and the repository is flushed and closed
+// - it runs once, later calls wait for the first one and return its error
+func (a *ServerChi) Shutdown(ctx context.Context) (err error) {
+	err = a.shutdown(ctx, a.drainDelay)
+	return
+}
+
+// shutdown is a method that stops the application gracefully after failing the readiness probe for drainDelay
+// - ctx bounds the drain delay too, once it is done the server is closed right away
+func (a *ServerChi) shutdown(ctx context.Context, drainDelay time.Duration) (err error) {
+	a.shutdownOnce.Do(func() {
+		a.draining.Store(true)
+		if drainDelay > 0 {
+			timer := time.NewTimer(drainDelay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+		err := a.server.Shutdown(ctx)
+		if err != nil {
+			// drop the connections that did not finish in time
+			a.server.Close()
+		}
+		a.shutdownErr = errors.Join(err, a.close())
+	})
+	err = a.shutdownErr
+	return
+}
+
+// onShutdown is a method that registers a resource to be released by Shutdown
+func (a *ServerChi) onShutdown(close func() error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closers = append(a.closers, close)
+}
+
+// close is a method that releases the resources registered by onShutdown, in reverse order
+func (a *ServerChi) close() (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		err = errors.Join(err, a.closers[i]())
+	}
+	a.closers = nil
+	return
+}
+
+// handler is a method that builds the dependencies of the application and returns its router
+func (a *ServerChi) handler() (h http.Handler, err error) {
 	// dependencies
 	// - loader
 	ld := loader.NewVehicleJSONFile(a.loaderFilePath)
-	db, err := ld.Load()
+	var db map[int]internal.Vehicle
+	switch a.registrationConflicts {
+	case RegistrationConflictsKeepFirst:
+		var skipped []internal.VehicleRegistrationConflict
+		db, skipped, err = ld.LoadKeepFirst()
+		for _, c := range skipped {
+			log.Printf("loader: registration %q is shared by ids %v, keeping %d and skipping the others", c.Registration, c.Ids, c.Ids[0])
+		}
+	default:
+		db, err = ld.Load()
+		if errors.Is(err, internal.ErrVehicleRegistrationConflict) {
+			err = fmt.Errorf("%w (set registration_conflicts to %s to skip all but the lowest id of each)", err, RegistrationConflictsKeepFirst)
+		}
+	}
+	if err != nil {
+		return
+	}
+	// - the loaded vehicles follow the same rules as the writes, otherwise they could not be updated later
+	err = repository.ValidateVehicles(db)
 	if err != nil {
 		return
 	}
 	// - repository
-	rp := repository.NewVehicleMap(db)
+	var rp internal.VehicleRepository
+	switch a.repositoryType {
+	case RepositoryMap:
+		rp = repository.NewVehicleMap(db)
+	case RepositoryFile:
+		rp = repository.NewVehicleFile(db, ld)
+	case RepositoryLog:
+		var rpLog *repository.VehicleLog
+		rpLog, err = repository.NewVehicleLog(db, ld, a.logFilePath, a.logCompactEvery)
+		if err != nil {
+			return
+		}
+		a.onShutdown(rpLog.Close)
+		rp = rpLog
+	case RepositorySQL:
+		var conn *sql.DB
+		conn, err = sql.Open("sqlite", a.sqlDataSource)
+		if err != nil {
+			return
+		}
+		a.onShutdown(conn.Close)
+		// sqlite allows a single writer at a time
+		conn.SetMaxOpenConns(1)
+		rpSQL := repository.NewVehicleSQL(conn)
+		err = rpSQL.Migrate()
+		if err != nil {
+			return
+		}
+		// - seed the database with the loader file on first run
+		var v map[int]internal.Vehicle
+		v, err = rpSQL.FindAll(context.Background())
+		if err != nil {
+			return
+		}
+		if len(v) == 0 {
+			err = rpSQL.Seed(db)
+			if err != nil {
+				return
+			}
+		}
+		rp = rpSQL
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownRepository, a.repositoryType)
+		return
+	}
+	// - metrics, the health checks below use rp directly so that the probes are not recorded
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	rpInstrumented := repository.NewVehicleInstrumented(rp)
+	reg.MustRegister(rpInstrumented)
+	mt := newHTTPMetrics(reg)
 	// - service
-	sv := service.NewVehicleDefault(rp)
+	sv := service.NewVehicleDefault(rpInstrumented)
 	// - handler
 	hd := handler.NewVehicleDefault(sv)
+	hdV2 := handler.NewVehicleV2(sv)
+	hh := handler.NewHealth(
+		// a lookup of an id that is never allocated goes through the whole repository, e.g. a query on sql
+		handler.HealthCheck{Name: "repository", Check: func(ctx context.Context) error {
+			_, err := rp.FindById(ctx, 0)
+			if err != nil && !errors.Is(err, internal.ErrVehicleNotFound) {
+				return err
+			}
+			return nil
+		}},
+		handler.HealthCheck{Name: "server", Check: func(ctx context.Context) error {
+			if a.draining.Load() {
+				return ErrShuttingDown
+			}
+			return nil
+		}},
+	)
 	// router
 	rt := chi.NewRouter()
 	// - middlewares
 	rt.Use(middleware.Logger)
+	// - outside Recoverer, so that panics are recorded as 500
+	rt.Use(mt.middleware)
 	rt.Use(middleware.Recoverer)
+	rt.Use(requestTimeout(a.requestTimeout))
 	// - endpoints
+	// - GET /healthz
+	rt.Get("/healthz", hh.Healthz())
+	// - GET /readyz
+	rt.Get("/readyz", hh.Readyz())
+	// - GET /version
+	rt.Get("/version", hh.Version())
+	// - GET /metrics
+	rt.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
 	rt.Route("/vehicles", func(rt chi.Router) {
 		// - GET /vehicles
 		rt.Get("/", hd.GetAll())
@@ -78,17 +429,79 @@ func (a *ServerChi) Run() (err error) {
 		rt.Post("/batch", hd.BatchCreate())
 		// - GET /vehicles/color/{color}/year/{year}
 		rt.Get("/color/{color}/year/{year}", hd.GetByColorAndYear())
+		// - GET /vehicles/{id}
+		rt.Get("/{id}", hd.GetById())
+		// - GET /vehicles/registration/{registration}
+		rt.Get("/registration/{registration}", hd.GetByRegistration())
+		// - PUT /vehicles/{id}
+		rt.Put("/{id}", hd.Update())
+		// - PATCH /vehicles/{id}
+		rt.Patch("/{id}", hd.Patch())
 		// - DELETE /vehicles/{id}
 		rt.Delete("/{id}", hd.Delete())
 		// - PUT /vehicles/{id}/fuel-type
 		rt.Put("/{id}/fuel-type", hd.UpdateFuelType())
+		// - PUT /vehicles/{id}/update_speed
+		rt.Put("/{id}/update_speed", hd.UpdateMaxSpeed())
 		// - GET /weight?min={weight_min}&max={weight_max}
 		rt.Get("/weight", hd.GetByWeightRange())
 		// - GET /vehicles/brand/{brand}/between/{start_year}/{end_year}
 		rt.Get("/brand/{brand}/between/{start_year}/{end_year}", hd.GetByBrandAndYearRange())
+		// - GET /vehicles/average_speed/brand/{brand}
+		rt.Get("/average_speed/brand/{brand}", hd.GetAverageMaxSpeedByBrand())
+		// - GET /vehicles/fuel_type/{type}
+		rt.Get("/fuel_type/{type}", hd.GetByFuelType())
+		// - GET /vehicles/transmission/{type}
+		rt.Get("/transmission/{type}", hd.GetByTransmission())
+		// - GET /vehicles/average_capacity/brand/{brand}
+		rt.Get("/average_capacity/brand/{brand}", hd.GetAverageCapacityByBrand())
+		// - GET /vehicles/dimensions?length={min_length}-{max_length}&width={min_width}-{max_width}
+		rt.Get("/dimensions", hd.GetByDimensions())
+		// - GET /vehicles/search?{field}[{operator}]={value}
+		rt.Get("/search", hd.Search())
+	})
+	// - version 2: every collection responds with the same list envelope
+	rt.Route("/v2/vehicles", func(rt chi.Router) {
+		// - GET /v2/vehicles
+		rt.Get("/", hdV2.GetAll())
+		// - POST /v2/vehicles
+		rt.Post("/", hdV2.Create())
+		// - POST /v2/vehicles/batch
+		rt.Post("/batch", hdV2.BatchCreate())
+		// - GET /v2/vehicles/color/{color}/year/{year}
+		rt.Get("/color/{color}/year/{year}", hdV2.GetByColorAndYear())
+		// - GET /v2/vehicles/{id}
+		rt.Get("/{id}", hdV2.GetById())
+		// - GET /v2/vehicles/registration/{registration}
+		rt.Get("/registration/{registration}", hdV2.GetByRegistration())
+		// - PUT /v2/vehicles/{id}
+		rt.Put("/{id}", hdV2.Update())
+		// - PATCH /v2/vehicles/{id}
+		rt.Patch("/{id}", hdV2.Patch())
+		// - DELETE /v2/vehicles/{id}
+		rt.Delete("/{id}", hdV2.Delete())
+		// - PUT /v2/vehicles/{id}/fuel-type
+		rt.Put("/{id}/fuel-type", hdV2.UpdateFuelType())
+		// - PUT /v2/vehicles/{id}/update_speed
+		rt.Put("/{id}/update_speed", hdV2.UpdateMaxSpeed())
+		// - GET /v2/vehicles/weight?min={weight_min}&max={weight_max}
+		rt.Get("/weight", hdV2.GetByWeightRange())
+		// - GET /v2/vehicles/brand/{brand}/between/{start_year}/{end_year}
+		rt.Get("/brand/{brand}/between/{start_year}/{end_year}", hdV2.GetByBrandAndYearRange())
+		// - GET /v2/vehicles/average_speed/brand/{brand}
+		rt.Get("/average_speed/brand/{brand}", hdV2.GetAverageMaxSpeedByBrand())
+		// - GET /v2/vehicles/fuel_type/{type}
+		rt.Get("/fuel_type/{type}", hdV2.GetByFuelType())
+		// - GET /v2/vehicles/transmission/{type}
+		rt.Get("/transmission/{type}", hdV2.GetByTransmission())
+		// - GET /v2/vehicles/average_capacity/brand/{brand}
+		rt.Get("/average_capacity/brand/{brand}", hdV2.GetAverageCapacityByBrand())
+		// - GET /v2/vehicles/dimensions?length={min_length}-{max_length}&width={min_width}-{max_width}
+		rt.Get("/dimensions", hdV2.GetByDimensions())
+		// - GET /v2/vehicles/search?{field}[{operator}]={value}
+		rt.Get("/search", hdV2.Search())
 	})
 
-	// run server
-	err = http.ListenAndServe(a.serverAddress, rt)
+	h = rt
 	return
 }
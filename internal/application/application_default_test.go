@@ -0,0 +1,187 @@
+package application_test
+
+import (
+	"app/internal"
+	"app/internal/application"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerChi_Shutdown(t *testing.T) {
+	t.Run("should be ready and record metrics, then stop serving and flush the repository", func(t *testing.T) {
+		// ARRANGE
+		// - loader file
+		dir := t.TempDir()
+		data, err := os.ReadFile("../../docs/db/vehicles_100.json")
+		require.NoError(t, err)
+		loaderFile := filepath.Join(dir, "vehicles.json")
+		require.NoError(t, os.WriteFile(loaderFile, data, 0o644))
+		// - free address
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		require.NoError(t, ln.Close())
+		// - application, without drain delay, request and shutdown timeouts
+		cfg := application.DefaultConfigServerChi()
+		cfg.ServerAddress = addr
+		cfg.LoaderFilePath = loaderFile
+		cfg.RepositoryType = application.RepositoryLog
+		cfg.RegistrationConflicts = application.RegistrationConflictsKeepFirst
+		cfg.DrainDelay = new(application.Duration)
+		cfg.RequestTimeout = new(application.Duration)
+		cfg.ShutdownTimeout = new(application.Duration)
+		app := application.NewServerChi(&cfg)
+		ran := make(chan error, 1)
+		go func() {
+			ran <- app.Run()
+		}()
+		// - wait until the server is up, then write through the log
+		require.Eventually(t, func() bool {
+			res, err := http.Get("http://" + addr + "/vehicles/1")
+			if err != nil {
+				return false
+			}
+			res.Body.Close()
+			return res.StatusCode == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+		res, err := http.Get("http://" + addr + "/readyz")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		res, err = http.Post("http://"+addr+"/vehicles", "application/json", strings.NewReader(`{"brand":"Ford","model":"Ka","registration":"SHT-0001","color":"Red","year":2019,"passengers":4,"max_speed":150,"fuel_type":"gasoline","transmission":"manual","weight":900,"height":1.4,"length":3.9,"width":1.6}`))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		// - the request is recorded by its route pattern
+		res, err = http.Get("http://" + addr + "/metrics")
+		require.NoError(t, err)
+		metrics, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		require.Contains(t, string(metrics), `http_requests_total{method="POST",route="/vehicles",status="201"} 1`)
+		require.Contains(t, string(metrics), `vehicle_repository_operations_total{operation="Create",result="ok"} 1`)
+
+		// - a connection dialed by the client but never used counts as active for 5 seconds on Shutdown
+		http.DefaultClient.CloseIdleConnections()
+
+		// ACT
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = app.Shutdown(ctx)
+
+		// ASSERT
+		require.NoError(t, err)
+		select {
+		case err := <-ran:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after Shutdown")
+		}
+		_, err = http.Get("http://" + addr + "/vehicles/1")
+		require.Error(t, err)
+		log, err := os.ReadFile(loaderFile + ".log")
+		require.NoError(t, err)
+		require.Contains(t, string(log), "SHT-0001")
+		// - a second call returns the result of the first one
+		require.NoError(t, app.Shutdown(ctx))
+	})
+
+	t.Run("should fail the readiness probe and keep serving during the drain delay", func(t *testing.T) {
+		// ARRANGE
+		// - free address
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		require.NoError(t, ln.Close())
+		// - application
+		cfg := application.DefaultConfigServerChi()
+		cfg.ServerAddress = addr
+		cfg.LoaderFilePath = "../../docs/db/vehicles_100.json"
+		cfg.RegistrationConflicts = application.RegistrationConflictsKeepFirst
+		cfg.DrainDelay = ptr(application.Duration(time.Second))
+		app := application.NewServerChi(&cfg)
+		ran := make(chan error, 1)
+		go func() {
+			ran <- app.Run()
+		}()
+		// - client without keep-alive, so that no idle connection delays the shutdown
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		require.Eventually(t, func() bool {
+			res, err := client.Get("http://" + addr + "/readyz")
+			if err != nil {
+				return false
+			}
+			res.Body.Close()
+			return res.StatusCode == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// ACT
+		shutdown := make(chan error, 1)
+		go func() {
+			shutdown <- app.Shutdown(context.Background())
+		}()
+
+		// ASSERT
+		require.Eventually(t, func() bool {
+			res, err := client.Get("http://" + addr + "/readyz")
+			if err != nil {
+				return false
+			}
+			res.Body.Close()
+			return res.StatusCode == http.StatusServiceUnavailable
+		}, 500*time.Millisecond, 10*time.Millisecond)
+		res, err := client.Get("http://" + addr + "/vehicles/1")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		select {
+		case err := <-shutdown:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Shutdown did not return after the drain delay")
+		}
+		select {
+		case err := <-ran:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after Shutdown")
+		}
+		_, err = client.Get("http://" + addr + "/readyz")
+		require.Error(t, err)
+	})
+
+	t.Run("should refuse to start on shared registrations by default and leave the loader file as it is", func(t *testing.T) {
+		// ARRANGE
+		// - loader file, the seed file has shared registrations
+		dir := t.TempDir()
+		data, err := os.ReadFile("../../docs/db/vehicles_100.json")
+		require.NoError(t, err)
+		loaderFile := filepath.Join(dir, "vehicles.json")
+		require.NoError(t, os.WriteFile(loaderFile, data, 0o644))
+		// - application
+		cfg := application.DefaultConfigServerChi()
+		cfg.ServerAddress = "127.0.0.1:0"
+		cfg.LoaderFilePath = loaderFile
+		cfg.RepositoryType = application.RepositoryFile
+		app := application.NewServerChi(&cfg)
+
+		// ACT
+		err = app.Run()
+
+		// ASSERT
+		require.ErrorIs(t, err, internal.ErrVehicleRegistrationConflict)
+		require.ErrorContains(t, err, "keep_first")
+		after, err := os.ReadFile(loaderFile)
+		require.NoError(t, err)
+		require.Equal(t, data, after)
+	})
+}
@@ -0,0 +1,269 @@
+package application
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables read by LoadConfigServerChi
+const EnvPrefix = "APP_"
+
+var (
+	// ErrInvalidConfig is an error that represents that the configuration can not be loaded or is not valid
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// configOption is a struct that represents an option of ConfigServerChi that can be set from every source
+type configOption struct {
+	// name is the key of the option in the config file, its flag is the name with dashes
+	// and its environment variable is the name in upper case prefixed by EnvPrefix
+	name string
+	// usage is the description of the option
+	usage string
+	// field is a function that returns a pointer to the field of the option, a *string, a **int or a **Duration
+	field func(c *ConfigServerChi) any
+}
+
+// configOptions is the list of options of ConfigServerChi
+var configOptions = []configOption{
+	{"server_address", "address where the server listens", func(c *ConfigServerChi) any { return &c.ServerAddress }},
+	{"loader_file_path", "path to the JSON file with the vehicles", func(c *ConfigServerChi) any { return &c.LoaderFilePath }},
+	{"repository_type", "repository of the vehicles: map, file, log or sql", func(c *ConfigServerChi) any { return &c.RepositoryType }},
+	{"registration_conflicts", "when several vehicles of the loader file share a registration: fail, or keep_first to keep the lowest id and skip the others", func(c *ConfigServerChi) any { return &c.RegistrationConflicts }},
+	{"log_file_path", "path to the log of the log repository (default the loader file path with a .log suffix)", func(c *ConfigServerChi) any { return &c.LogFilePath }},
+	{"log_compact_every", "log records after which the log repository writes a snapshot, 0 or negative to disable", func(c *ConfigServerChi) any { return &c.LogCompactEvery }},
+	{"sql_data_source", "SQLite data source of the sql repository", func(c *ConfigServerChi) any { return &c.SQLDataSource }},
+	{"read_timeout", "maximum duration for reading a request, 0 for none", func(c *ConfigServerChi) any { return &c.ReadTimeout }},
+	{"write_timeout", "maximum duration for writing a response, 0 for none", func(c *ConfigServerChi) any { return &c.WriteTimeout }},
+	{"idle_timeout", "maximum duration a keep-alive connection waits for the next request, 0 to use the read timeout", func(c *ConfigServerChi) any { return &c.IdleTimeout }},
+	{"drain_delay", "duration the readiness probe fails on SIGINT or SIGTERM before the server stops accepting connections, 0 for none", func(c *ConfigServerChi) any { return &c.DrainDelay }},
+	{"shutdown_timeout", "maximum duration in-flight requests are drained for on SIGINT or SIGTERM, 0 to wait for all of them", func(c *ConfigServerChi) any { return &c.ShutdownTimeout }},
+	{"request_timeout", "maximum duration the service and the repository spend on a request, past it 504 is returned, 0 for none", func(c *ConfigServerChi) any { return &c.RequestTimeout }},
+}
+
+// envName is a method that returns the environment variable of the option
+func (o configOption) envName() string {
+	return EnvPrefix + strings.ToUpper(o.name)
+}
+
+// flagName is a method that returns the flag of the option
+func (o configOption) flagName() string {
+	return strings.ReplaceAll(o.name, "_", "-")
+}
+
+// set is a method that parses raw into the field of the option
+func (o configOption) set(c *ConfigServerChi, raw string) (err error) {
+	switch field := o.field(c).(type) {
+	case *string:
+		*field = raw
+	case **int:
+		var v int
+		v, err = strconv.Atoi(raw)
+		if err == nil {
+			*field = &v
+		}
+	case **Duration:
+		var d Duration
+		err = d.UnmarshalText([]byte(raw))
+		if err == nil {
+			*field = &d
+		}
+	}
+	return
+}
+
+// ptr is a function that returns a pointer to a copy of v, to set the optional fields of ConfigServerChi
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// Duration is a time.Duration read and written in the format of time.ParseDuration, e.g. "15s"
+type Duration time.Duration
+
+// MarshalText is a method that returns the duration in the format of time.Duration.String
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText is a method that parses the duration with time.ParseDuration
+func (d *Duration) UnmarshalText(text []byte) (err error) {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return
+	}
+	*d = Duration(v)
+	return
+}
+
+// DefaultConfigServerChi is a function that returns the configuration used when no source sets an option
+func DefaultConfigServerChi() ConfigServerChi {
+	return ConfigServerChi{
+		ServerAddress:         ":8080",
+		LoaderFilePath:        "docs/db/vehicles_100.json",
+		RepositoryType:        RepositoryMap,
+		RegistrationConflicts: RegistrationConflictsFail, // keep_first drops vehicles for good with the file and log repositories, see README
+		LogCompactEvery:       ptr(1000),
+		SQLDataSource:         "vehicles.db",
+		ReadTimeout:           ptr(Duration(10 * time.Second)),
+		WriteTimeout:          ptr(Duration(30 * time.Second)),
+		IdleTimeout:           ptr(Duration(2 * time.Minute)),
+		DrainDelay:            ptr(Duration(5 * time.Second)),
+		ShutdownTimeout:       ptr(Duration(15 * time.Second)),
+		RequestTimeout:        ptr(Duration(10 * time.Second)),
+	}
+}
+
+// LoadConfigServerChi is a function that returns the configuration of ServerChi read from the command line arguments,
+// the environment variables and a config file, and whether --print-config was requested
+// - precedence, from highest to lowest: flags, environment variables, config file, DefaultConfigServerChi
+// - the config file is given by --config or APP_CONFIG_FILE, a .json, .yaml or .yml file with the keys of configOptions
+// - the resulting configuration is validated, flag.ErrHelp is returned if -h or --help was requested
+func LoadConfigServerChi(args []string, lookupEnv func(key string) (string, bool)) (cfg *ConfigServerChi, printConfig bool, err error) {
+	// flags, their values are applied after the config file and the environment
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := fs.String("config", "", fmt.Sprintf("path to a .json, .yaml or .yml config file (env %sCONFIG_FILE)", EnvPrefix))
+	fs.BoolVar(&printConfig, "print-config", false, "print the resulting configuration as JSON and exit")
+	flags := make(map[string]string)
+	for _, o := range configOptions {
+		o := o
+		fs.Func(o.flagName(), fmt.Sprintf("%s (env %s)", o.usage, o.envName()), func(raw string) error {
+			flags[o.name] = raw
+			return nil
+		})
+	}
+	err = fs.Parse(args)
+	if err != nil {
+		return
+	}
+
+	c := DefaultConfigServerChi()
+
+	// config file
+	if *configFile == "" {
+		*configFile, _ = lookupEnv(EnvPrefix + "CONFIG_FILE")
+	}
+	if *configFile != "" {
+		err = readConfigFile(*configFile, &c)
+		if err != nil {
+			return
+		}
+	}
+
+	// environment variables
+	for _, o := range configOptions {
+		raw, ok := lookupEnv(o.envName())
+		if !ok {
+			continue
+		}
+		if e := o.set(&c, raw); e != nil {
+			err = fmt.Errorf("%w: %s: %v", ErrInvalidConfig, o.envName(), e)
+			return
+		}
+	}
+
+	// flags
+	for _, o := range configOptions {
+		raw, ok := flags[o.name]
+		if !ok {
+			continue
+		}
+		if e := o.set(&c, raw); e != nil {
+			err = fmt.Errorf("%w: --%s: %v", ErrInvalidConfig, o.flagName(), e)
+			return
+		}
+	}
+
+	// derived defaults
+	if c.LogFilePath == "" {
+		c.LogFilePath = c.LoaderFilePath + ".log"
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return
+	}
+	cfg = &c
+	return
+}
+
+// readConfigFile is a function that decodes a config file onto cfg, rejecting unknown keys
+func readConfigFile(path string, cfg *ConfigServerChi) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		return
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+	default:
+		err = errors.New("unsupported extension, want .json, .yaml or .yml")
+	}
+	// an empty file sets nothing
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+		return
+	}
+	err = nil
+	return
+}
+
+// Validate is a method that checks that the configuration can be used to run ServerChi
+// - every problem is reported, joined in a single error wrapping ErrInvalidConfig
+func (c *ConfigServerChi) Validate() (err error) {
+	var errs []error
+	if _, _, e := net.SplitHostPort(c.ServerAddress); e != nil {
+		errs = append(errs, fmt.Errorf("%w: server_address %q: %v", ErrInvalidConfig, c.ServerAddress, e))
+	}
+	if c.LoaderFilePath == "" {
+		errs = append(errs, fmt.Errorf("%w: loader_file_path is required", ErrInvalidConfig))
+	} else if _, e := os.Stat(c.LoaderFilePath); e != nil {
+		errs = append(errs, fmt.Errorf("%w: loader_file_path: %v", ErrInvalidConfig, e))
+	}
+	switch c.RepositoryType {
+	case RepositoryMap, RepositoryFile, RepositoryLog:
+	case RepositorySQL:
+		if c.SQLDataSource == "" {
+			errs = append(errs, fmt.Errorf("%w: sql_data_source is required by the %s repository", ErrInvalidConfig, RepositorySQL))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: repository_type %q, want %s, %s, %s or %s: %w", ErrInvalidConfig, c.RepositoryType, RepositoryMap, RepositoryFile, RepositoryLog, RepositorySQL, ErrUnknownRepository))
+	}
+	switch c.RegistrationConflicts {
+	case RegistrationConflictsFail, RegistrationConflictsKeepFirst:
+	default:
+		errs = append(errs, fmt.Errorf("%w: registration_conflicts %q, want %s or %s", ErrInvalidConfig, c.RegistrationConflicts, RegistrationConflictsFail, RegistrationConflictsKeepFirst))
+	}
+
+	// unset durations take their default in NewServerChi
+	for _, d := range []struct {
+		name  string
+		value *Duration
+	}{{"read_timeout", c.ReadTimeout}, {"write_timeout", c.WriteTimeout}, {"idle_timeout", c.IdleTimeout}, {"drain_delay", c.DrainDelay}, {"shutdown_timeout", c.ShutdownTimeout}, {"request_timeout", c.RequestTimeout}} {
+		if d.value != nil && *d.value < 0 {
+			errs = append(errs, fmt.Errorf("%w: %s %s can not be negative", ErrInvalidConfig, d.name, time.Duration(*d.value)))
+		}
+	}
+
+	err = errors.Join(errs...)
+	return
+}
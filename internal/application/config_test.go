@@ -0,0 +1,148 @@
+package application_test
+
+import (
+	"app/internal/application"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ptr is a function that returns a pointer to a copy of v
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// env is a function that returns a lookup function over the given environment variables
+func env(vars map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func TestLoadConfigServerChi(t *testing.T) {
+	t.Run("should apply flags over environment variables over the config file over the defaults", func(t *testing.T) {
+		// ARRANGE
+		// - loader file and config file
+		dir := t.TempDir()
+		loaderFile := filepath.Join(dir, "vehicles.json")
+		require.NoError(t, os.WriteFile(loaderFile, []byte("[]"), 0o644))
+		configFile := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(configFile, []byte("server_address: \":9000\"\nrepository_type: log\nloader_file_path: "+loaderFile+"\nlog_compact_every: 10\nshutdown_timeout: 5s\n"), 0o644))
+
+		// ACT
+		cfg, printConfig, err := application.LoadConfigServerChi(
+			[]string{"--config", configFile, "--repository-type", "sql", "--write-timeout", "1m", "--print-config"},
+			env(map[string]string{"APP_SERVER_ADDRESS": ":7000", "APP_REPOSITORY_TYPE": "file", "APP_REGISTRATION_CONFLICTS": "keep_first"}),
+		)
+
+		// ASSERT
+		require.NoError(t, err)
+		require.True(t, printConfig)
+		require.Equal(t, &application.ConfigServerChi{
+			ServerAddress:         ":7000",
+			LoaderFilePath:        loaderFile,
+			RepositoryType:        "sql",
+			RegistrationConflicts: "keep_first",
+			LogFilePath:           loaderFile + ".log",
+			LogCompactEvery:       ptr(10),
+			SQLDataSource:         "vehicles.db",
+			ReadTimeout:           ptr(application.Duration(10 * time.Second)),
+			WriteTimeout:          ptr(application.Duration(time.Minute)),
+			IdleTimeout:           ptr(application.Duration(2 * time.Minute)),
+			DrainDelay:            ptr(application.Duration(5 * time.Second)),
+			ShutdownTimeout:       ptr(application.Duration(5 * time.Second)),
+			RequestTimeout:        ptr(application.Duration(10 * time.Second)),
+		}, cfg)
+	})
+
+	t.Run("should read the config file given by the environment", func(t *testing.T) {
+		// ARRANGE
+		// - loader file and config file
+		dir := t.TempDir()
+		loaderFile := filepath.Join(dir, "vehicles.json")
+		require.NoError(t, os.WriteFile(loaderFile, []byte("[]"), 0o644))
+		configFile := filepath.Join(dir, "config.json")
+		require.NoError(t, os.WriteFile(configFile, []byte(`{"loader_file_path":"`+loaderFile+`","log_file_path":"changes.log"}`), 0o644))
+
+		// ACT
+		cfg, _, err := application.LoadConfigServerChi(nil, env(map[string]string{"APP_CONFIG_FILE": configFile}))
+
+		// ASSERT
+		require.NoError(t, err)
+		require.Equal(t, loaderFile, cfg.LoaderFilePath)
+		require.Equal(t, "changes.log", cfg.LogFilePath)
+		require.Equal(t, ":8080", cfg.ServerAddress)
+		require.Equal(t, application.RegistrationConflictsFail, cfg.RegistrationConflicts)
+	})
+
+	t.Run("should keep a zero duration, which disables the timeout", func(t *testing.T) {
+		// ARRANGE
+		// - loader file
+		dir := t.TempDir()
+		loaderFile := filepath.Join(dir, "vehicles.json")
+		require.NoError(t, os.WriteFile(loaderFile, []byte("[]"), 0o644))
+
+		// ACT
+		cfg, _, err := application.LoadConfigServerChi(
+			[]string{"--loader-file-path", loaderFile, "--request-timeout", "0"},
+			env(map[string]string{"APP_SHUTDOWN_TIMEOUT": "0s"}),
+		)
+
+		// ASSERT
+		require.NoError(t, err)
+		require.Equal(t, ptr(application.Duration(0)), cfg.RequestTimeout)
+		require.Equal(t, ptr(application.Duration(0)), cfg.ShutdownTimeout)
+		require.Equal(t, ptr(application.Duration(10*time.Second)), cfg.ReadTimeout)
+	})
+
+	t.Run("should tell an omitted option from a zero one in a partial configuration", func(t *testing.T) {
+		// ARRANGE
+		var cfg application.ConfigServerChi
+
+		// ACT
+		err := json.Unmarshal([]byte(`{"request_timeout":"0s","log_compact_every":0}`), &cfg)
+
+		// ASSERT
+		// - omitted options stay unset, so that NewServerChi applies their default
+		require.NoError(t, err)
+		require.Equal(t, ptr(application.Duration(0)), cfg.RequestTimeout)
+		require.Equal(t, ptr(0), cfg.LogCompactEvery)
+		require.Nil(t, cfg.ReadTimeout)
+		require.Nil(t, cfg.ShutdownTimeout)
+	})
+
+	t.Run("should fail when a source is malformed or the result is not valid", func(t *testing.T) {
+		// ARRANGE
+		dir := t.TempDir()
+		unknownKey := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(unknownKey, []byte("port: 8080\n"), 0o644))
+		cases := map[string]struct {
+			args []string
+			vars map[string]string
+		}{
+			"unknown key in the config file": {args: []string{"--config", unknownKey}},
+			"missing config file":            {args: []string{"--config", filepath.Join(dir, "missing.yaml")}},
+			"non numeric environment value":  {vars: map[string]string{"APP_LOG_COMPACT_EVERY": "many"}},
+			"address without port":           {args: []string{"--server-address", "localhost"}},
+			"unknown repository":             {vars: map[string]string{"APP_REPOSITORY_TYPE": "mongo"}},
+			"unknown conflict policy":        {args: []string{"--registration-conflicts", "keep_last"}},
+			"missing loader file":            {args: []string{"--loader-file-path", filepath.Join(dir, "missing.json")}},
+			"malformed duration":             {vars: map[string]string{"APP_SHUTDOWN_TIMEOUT": "15"}},
+			"negative duration":              {args: []string{"--read-timeout", "-1s"}},
+			"negative drain delay":           {vars: map[string]string{"APP_DRAIN_DELAY": "-5s"}},
+		}
+		for name, c := range cases {
+			// ACT
+			cfg, _, err := application.LoadConfigServerChi(c.args, env(c.vars))
+
+			// ASSERT
+			require.ErrorIs(t, err, application.ErrInvalidConfig, name)
+			require.Nil(t, cfg, name)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package application
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// routeUnmatched is the route label of the requests that match no route
+const routeUnmatched = "unmatched"
+
+// newHTTPMetrics is a function that returns a new instance of httpMetrics, registered in reg
+func newHTTPMetrics(reg prometheus.Registerer) *httpMetrics {
+	m := &httpMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of the HTTP requests, by method, route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+	reg.MustRegister(m.requests, m.durations)
+	return m
+}
+
+// httpMetrics is a struct that represents the metrics of the HTTP routes
+// - routes are labelled by their chi pattern, e.g. /vehicles/{id}, so that the cardinality is bounded
+type httpMetrics struct {
+	// requests is the number of requests, by method, route and status
+	requests *prometheus.CounterVec
+	// durations is the duration of the requests, by method, route and status
+	durations *prometheus.HistogramVec
+}
+
+// middleware is a method that records the count and the duration of every request
+func (m *httpMetrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		// the pattern is known once the request is routed
+		route := routeUnmatched
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+		m.requests.With(labels).Inc()
+		m.durations.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
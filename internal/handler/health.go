@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/bootcamp-go/web/response"
+)
+
+// HealthCheck is a struct that represents a component verified by the readiness probe
+type HealthCheck struct {
+	// Name is the name of the component
+	Name string
+	// Check is a function that returns an error if the component can not serve requests
+	Check func(ctx context.Context) error
+}
+
+// NewHealth is a function that returns a new instance of Health
+func NewHealth(checks ...HealthCheck) *Health {
+	return &Health{checks: checks}
+}
+
+// Health is a struct with methods that represent handlers for the probes of the orchestrator
+type Health struct {
+	// checks is the list of components verified by Readyz, in order
+	checks []HealthCheck
+}
+
+// HealthCheckJSON is a struct that represents the result of a check in JSON format
+type HealthCheckJSON struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// VersionJSON is a struct that represents the build info of the binary in JSON format
+type VersionJSON struct {
+	Path      string `json:"path"`
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+const (
+	// statusOk is the status of a probe or a check that passes
+	statusOk = "ok"
+	// statusFail is the status of a probe or a check that fails
+	statusFail = "fail"
+)
+
+// Healthz is a method that returns a handler for the route GET /healthz
+// - it succeeds as long as the process serves requests
+func (h *Health) Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"status": statusOk,
+		})
+	}
+}
+
+// Readyz is a method that returns a handler for the route GET /readyz
+// - every check runs, the route responds 503 Service Unavailable if any of them fails
+func (h *Health) Readyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// process
+		code, status := http.StatusOK, statusOk
+		checks := make([]HealthCheckJSON, len(h.checks))
+		for i, c := range h.checks {
+			checks[i] = HealthCheckJSON{Name: c.Name, Status: statusOk}
+			if err := c.Check(r.Context()); err != nil {
+				checks[i].Status = statusFail
+				checks[i].Error = err.Error()
+				code, status = http.StatusServiceUnavailable, statusFail
+			}
+		}
+
+		// response
+		response.JSON(w, code, map[string]any{
+			"status": status,
+			"checks": checks,
+		})
+	}
+}
+
+// Version is a method that returns a handler for the route GET /version
+// - the build info is read from the binary, the vcs fields are only set when it was built from a repository
+func (h *Health) Version() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// process
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			response.Error(w, http.StatusInternalServerError, "Información de compilación no disponible.")
+			return
+		}
+		data := VersionJSON{
+			Path:      info.Main.Path,
+			Version:   info.Main.Version,
+			GoVersion: info.GoVersion,
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				data.Revision = s.Value
+			case "vcs.time":
+				data.Time = s.Value
+			case "vcs.modified":
+				data.Modified = s.Value == "true"
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, data)
+	}
+}
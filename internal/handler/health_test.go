@@ -0,0 +1,73 @@
+package handler_test
+
+import (
+	"app/internal/handler"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthz(t *testing.T) {
+	t.Run("should return status code 200 even when a readiness check fails", func(t *testing.T) {
+		// ARRANGE
+		// - handler
+		h := handler.NewHealth(handler.HealthCheck{Name: "repository", Check: func(ctx context.Context) error {
+			return errors.New("unreachable")
+		}})
+		reqHandler := h.Healthz()
+		// - request and response recorder
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		rr := httptest.NewRecorder()
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+	})
+}
+
+func TestReadyz(t *testing.T) {
+	t.Run("should return status code 200 when every check passes", func(t *testing.T) {
+		// ARRANGE
+		// - handler
+		ok := func(ctx context.Context) error { return nil }
+		h := handler.NewHealth(handler.HealthCheck{Name: "repository", Check: ok}, handler.HealthCheck{Name: "server", Check: ok})
+		reqHandler := h.Readyz()
+		// - request and response recorder
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rr := httptest.NewRecorder()
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"status":"ok","checks":[{"name":"repository","status":"ok"},{"name":"server","status":"ok"}]}`, rr.Body.String())
+	})
+
+	t.Run("should return status code 503 reporting the checks that fail", func(t *testing.T) {
+		// ARRANGE
+		// - handler
+		h := handler.NewHealth(
+			handler.HealthCheck{Name: "repository", Check: func(ctx context.Context) error { return nil }},
+			handler.HealthCheck{Name: "server", Check: func(ctx context.Context) error { return errors.New("shutting down") }},
+		)
+		reqHandler := h.Readyz()
+		// - request and response recorder
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rr := httptest.NewRecorder()
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		require.JSONEq(t, `{"status":"fail","checks":[{"name":"repository","status":"ok"},{"name":"server","status":"fail","error":"shutting down"}]}`, rr.Body.String())
+	})
+}
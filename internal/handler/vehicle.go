@@ -2,14 +2,48 @@ package handler
 
 import (
 	"app/internal"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/bootcamp-go/web/response"
 	"github.com/go-chi/chi/v5"
 )
 
+var (
+	// errInvalidRange is an error that represents a range query parameter that is not in the format {min}-{max}
+	errInvalidRange = errors.New("invalid range")
+)
+
+// parseRange is a function that parses a range query parameter in the format {min}-{max}
+func parseRange(s string) (min, max float64, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		err = errInvalidRange
+		return
+	}
+	min, err = strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return
+	}
+	max, err = strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return
+	}
+	if min > max {
+		err = errInvalidRange
+		return
+	}
+	return
+}
+
 // VehicleJSON is a struct that represents a vehicle in JSON format
 type VehicleJSON struct {
 	ID              int     `json:"id"`
@@ -28,6 +62,31 @@ type VehicleJSON struct {
 	Width           float64 `json:"width"`
 }
 
+// vehicleJSON is a function that returns the JSON representation of a vehicle
+func vehicleJSON(v internal.Vehicle) VehicleJSON {
+	return VehicleJSON{
+		ID:              v.Id,
+		Brand:           v.Brand,
+		Model:           v.Model,
+		Registration:    v.Registration,
+		Color:           v.Color,
+		FabricationYear: v.FabricationYear,
+		Capacity:        v.Capacity,
+		MaxSpeed:        v.MaxSpeed,
+		FuelType:        string(v.FuelType),
+		Transmission:    string(v.Transmission),
+		Weight:          v.Weight,
+		Height:          v.Height,
+		Length:          v.Length,
+		Width:           v.Width,
+	}
+}
+
+// vehicle is a method that returns the vehicle represented by the JSON, with the fuel type and transmission normalized
+func (v VehicleJSON) vehicle() *internal.Vehicle {
+	return internal.NewVehicle(v.ID, v.Brand, v.Model, v.Registration, v.Color, v.FabricationYear, v.Capacity, v.MaxSpeed, internal.NormalizeFuelType(v.FuelType), internal.NormalizeTransmission(v.Transmission), v.Weight, v.Height, v.Length, v.Width)
+}
+
 // VehicleBatchJSON is a struct that represents a list of vehicles in JSON format
 type VehicleBatchJSON struct {
 	Vehicles []VehicleJSON `json:"vehicles"`
@@ -38,6 +97,147 @@ type UpdateFuelTypeJSON struct {
 	FuelType string `json:"fuel_type"`
 }
 
+// UpdateMaxSpeedJSON is a struct that represents the request body for the route PUT /vehicles/{id}/update_speed
+type UpdateMaxSpeedJSON struct {
+	MaxSpeed float64 `json:"max_speed"`
+}
+
+// BatchItemErrorJSON is a struct that represents the error of a vehicle of a batch in JSON format
+type BatchItemErrorJSON struct {
+	Index   int              `json:"index"`
+	ID      int              `json:"id"`
+	Message string           `json:"message"`
+	Errors  []FieldErrorJSON `json:"errors,omitempty"`
+}
+
+// FieldErrorJSON is a struct that represents a validation rule broken by a field of a vehicle in JSON format
+type FieldErrorJSON struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fieldErrorsJSON is a function that returns the message of every rule broken by a vehicle
+func fieldErrorsJSON(e *internal.VehicleValidationError) (errs []FieldErrorJSON) {
+	errs = make([]FieldErrorJSON, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = FieldErrorJSON{Field: f.Field}
+		switch f.Rule {
+		case internal.RuleRequired:
+			errs[i].Message = "Campo obligatorio."
+		case internal.RulePositive:
+			errs[i].Message = "Debe ser mayor que 0."
+		case internal.RuleMin:
+			errs[i].Message = fmt.Sprintf("Debe ser mayor o igual que %v.", f.Limit)
+		case internal.RuleMax:
+			errs[i].Message = fmt.Sprintf("Debe ser menor o igual que %v.", f.Limit)
+		case internal.RuleOneOf:
+			errs[i].Message = fmt.Sprintf("Debe ser uno de: %s.", strings.Join(f.Allowed, ", "))
+		default:
+			errs[i].Message = "Valor no válido."
+		}
+	}
+	return
+}
+
+// validationError is a function that responds with every rule broken by a vehicle
+func validationError(w http.ResponseWriter, e *internal.VehicleValidationError) {
+	response.JSON(w, http.StatusBadRequest, map[string]any{
+		"status":  http.StatusText(http.StatusBadRequest),
+		"message": "Datos del vehículo no válidos.",
+		"errors":  fieldErrorsJSON(e),
+	})
+}
+
+// PageMetaJSON is a struct that represents the pagination metadata of a list of vehicles in JSON format
+type PageMetaJSON struct {
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// ListJSON is a struct that represents a page of a list of vehicles in JSON format
+type ListJSON struct {
+	Message string       `json:"message"`
+	Data    []any        `json:"data"`
+	Meta    PageMetaJSON `json:"meta"`
+}
+
+// isPageRequest is a function that returns whether the query parameters ask for a paginated list
+// - without them the list routes keep responding with a map of vehicles by id
+func isPageRequest(params url.Values) bool {
+	for _, key := range []string{internal.QueryParamSort, internal.QueryParamLimit, internal.QueryParamOffset, internal.QueryParamFields} {
+		if params.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverError is a function that responds with an error the service could not handle
+// - 504 if the deadline of the request was exceeded, 503 if the request was cancelled and 500 otherwise
+func serverError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		response.Error(w, http.StatusGatewayTimeout, "Tiempo de espera agotado.")
+	case errors.Is(err, context.Canceled):
+		response.Error(w, http.StatusServiceUnavailable, "Servicio no disponible.")
+	default:
+		response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+	}
+}
+
+// queryError is a function that responds with the message of an invalid query
+func queryError(w http.ResponseWriter, err error) {
+	var queryErr *internal.VehicleQueryError
+	if !errors.As(err, &queryErr) {
+		response.Error(w, http.StatusBadRequest, "Filtros mal formados.")
+		return
+	}
+	switch queryErr.Err {
+	case internal.ErrVehicleQueryUnknownField:
+		response.Errorf(w, http.StatusBadRequest, "Campo desconocido: %s.", queryErr.Field)
+	case internal.ErrVehicleQueryInvalidOperator:
+		response.Errorf(w, http.StatusBadRequest, "Operador no admitido para el campo %s: %s.", queryErr.Field, queryErr.Operator)
+	default:
+		response.Errorf(w, http.StatusBadRequest, "Valor mal formado para el campo %s: %s.", queryErr.Field, queryErr.Value)
+	}
+}
+
+// findPage is a method that responds with a page of the vehicles matching the query
+// - fields restricts the attributes of each vehicle, all of them if empty
+func (h *VehicleDefault) findPage(w http.ResponseWriter, r *http.Request, message string, q internal.VehicleQuery, fields []string) {
+	// process
+	p, err := h.sv.FindPage(r.Context(), q)
+	if err != nil {
+		var queryErr *internal.VehicleQueryError
+		if errors.As(err, &queryErr) {
+			queryError(w, err)
+			return
+		}
+		serverError(w, err)
+		return
+	}
+
+	// response
+	data := make([]any, len(p.Vehicles))
+	for i, value := range p.Vehicles {
+		if len(fields) > 0 {
+			projection := make(map[string]any, len(fields))
+			for _, field := range fields {
+				projection[field] = internal.VehicleFields[field].Value(value)
+			}
+			data[i] = projection
+			continue
+		}
+		data[i] = vehicleJSON(value)
+	}
+	response.JSON(w, http.StatusOK, ListJSON{
+		Message: message,
+		Data:    data,
+		Meta:    PageMetaJSON{Total: p.Total, Limit: q.Limit, Offset: q.Offset},
+	})
+}
+
 // NewVehicleDefault is a function that returns a new instance of VehicleDefault
 func NewVehicleDefault(sv internal.VehicleService) *VehicleDefault {
 	return &VehicleDefault{sv: sv}
@@ -49,39 +249,70 @@ type VehicleDefault struct {
 	sv internal.VehicleService
 }
 
-// GetAll is a method that returns a handler for the route GET /vehicles
+// list is a method that responds with a page of the vehicles matching the filters
+// - the ordering, pagination and projection are read from the query params
+func (h *VehicleDefault) list(w http.ResponseWriter, r *http.Request, filters ...internal.VehicleFilter) {
+	q, err := internal.ParseVehicleQueryOptions(r.URL.Query())
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	fields, err := internal.ParseVehicleFields(r.URL.Query().Get(internal.QueryParamFields))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	q.Filters = filters
+
+	h.findPage(w, r, "Vehículos encontrados exitosamente.", q, fields)
+}
+
+// eq is a function that returns a filter matching the vehicles whose field equals the value
+func eq(field string, value any) internal.VehicleFilter {
+	return internal.VehicleFilter{Field: field, Operator: internal.OpEq, Values: []any{value}}
+}
+
+// between is a function that returns the filters matching the vehicles whose field is within the inclusive range
+func between(field string, min, max any) []internal.VehicleFilter {
+	return []internal.VehicleFilter{
+		{Field: field, Operator: internal.OpGte, Values: []any{min}},
+		{Field: field, Operator: internal.OpLte, Values: []any{max}},
+	}
+}
+
+// GetAll is a method that returns a handler for the route GET /vehicles?sort={fields}&limit={limit}&offset={offset}&fields={fields}
+// - without sort, limit, offset or fields it responds with a map of all vehicles by id
 func (h *VehicleDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
-		// ...
+		// - get pagination from query params
+		if isPageRequest(r.URL.Query()) {
+			q, err := internal.ParseVehicleQueryOptions(r.URL.Query())
+			if err != nil {
+				queryError(w, err)
+				return
+			}
+			fields, err := internal.ParseVehicleFields(r.URL.Query().Get(internal.QueryParamFields))
+			if err != nil {
+				queryError(w, err)
+				return
+			}
+			h.findPage(w, r, "success", q, fields)
+			return
+		}
 
 		// process
 		// - get all vehicles
-		v, err := h.sv.FindAll()
+		v, err := h.sv.FindAll(r.Context())
 		if err != nil {
-			response.JSON(w, http.StatusInternalServerError, nil)
+			serverError(w, err)
 			return
 		}
 
 		// response
 		data := make(map[int]VehicleJSON)
 		for key, value := range v {
-			data[key] = VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			}
+			data[key] = vehicleJSON(value)
 		}
 		response.JSON(w, http.StatusOK, map[string]any{
 			"message": "success",
@@ -90,6 +321,68 @@ func (h *VehicleDefault) GetAll() http.HandlerFunc {
 	}
 }
 
+// GetById is a method that returns a handler for the route GET /vehicles/{id}
+func (h *VehicleDefault) GetById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from URL using chi
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Identificador mal formado.")
+			return
+		}
+
+		// process
+		// - call the service to get the vehicle by id
+		v, err := h.sv.FindById(r.Context(), id)
+		if err != nil {
+			switch err {
+			case internal.ErrVehicleNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontró el vehículo con ese identificador.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículo encontrado exitosamente.",
+			"data":    vehicleJSON(v),
+		})
+	}
+}
+
+// GetByRegistration is a method that returns a handler for the route GET /vehicles/registration/{registration}
+func (h *VehicleDefault) GetByRegistration() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get registration from URL using chi
+		registration := chi.URLParam(r, "registration")
+
+		// process
+		// - call the service to get the vehicle by registration
+		v, err := h.sv.FindByRegistration(r.Context(), registration)
+		if err != nil {
+			switch err {
+			case internal.ErrVehicleNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontró el vehículo con esa matrícula.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículo encontrado exitosamente.",
+			"data":    vehicleJSON(v),
+		})
+	}
+}
+
 // Create is a method that returns a handler for the route POST /vehicles
 func (h *VehicleDefault) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -102,42 +395,38 @@ func (h *VehicleDefault) Create() http.HandlerFunc {
 		}
 
 		// process
-		vehicle := internal.NewVehicle(
-			reqBody.ID,
-			reqBody.Brand,
-			reqBody.Model,
-			reqBody.Registration,
-			reqBody.Color,
-			reqBody.FabricationYear,
-			reqBody.Capacity,
-			reqBody.MaxSpeed,
-			reqBody.FuelType,
-			reqBody.Transmission,
-			reqBody.Weight,
-			reqBody.Height,
-			reqBody.Length,
-			reqBody.Width,
-		)
+		vehicle := reqBody.vehicle()
 
 		// call the service to create the vehicle
-		err = h.sv.Create(vehicle)
+		err = h.sv.Create(r.Context(), vehicle)
 		if err != nil {
+			var validationErr *internal.VehicleValidationError
+			if errors.As(err, &validationErr) {
+				validationError(w, validationErr)
+				return
+			}
 			switch err {
 			case internal.ErrVehicleAlreadyExists:
 				response.Error(w, http.StatusConflict, "Identificador del vehículo ya existente.")
 				return
+			case internal.ErrVehicleRegistrationAlreadyExists:
+				response.Error(w, http.StatusConflict, "Matrícula del vehículo ya existente.")
+				return
 			case internal.ErrVehicleMandatoryFields:
 				response.Error(w, http.StatusBadRequest, "Datos del vehículo mal formados o incompletos.")
 				return
 			default:
-				response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+				serverError(w, err)
 				return
 			}
 		}
 
 		// response
+		// - the id may have been allocated by the repository
+		w.Header().Set("Location", path.Join(r.URL.Path, strconv.Itoa(vehicle.Id)))
 		response.JSON(w, http.StatusCreated, map[string]any{
 			"message": "Vehículo creado exitosamente.",
+			"data":    vehicleJSON(*vehicle),
 		})
 	}
 }
@@ -157,27 +446,50 @@ func (h *VehicleDefault) BatchCreate() http.HandlerFunc {
 		// make a slice of pointers
 		vehicles := make([]*internal.Vehicle, len(reqBody.Vehicles))
 		for i, v := range reqBody.Vehicles {
-			vehicles[i] = internal.NewVehicle(
-				v.ID,
-				v.Brand,
-				v.Model,
-				v.Registration,
-				v.Color,
-				v.FabricationYear,
-				v.Capacity,
-				v.MaxSpeed,
-				v.FuelType,
-				v.Transmission,
-				v.Weight,
-				v.Height,
-				v.Length,
-				v.Width,
-			)
+			vehicles[i] = v.vehicle()
 		}
 
 		// call the service to create the vehicles
-		err = h.sv.BatchCreate(vehicles)
+		err = h.sv.BatchCreate(r.Context(), vehicles)
 		if err != nil {
+			var batchErr *internal.VehicleBatchError
+			if errors.As(err, &batchErr) {
+				// report every failing vehicle, conflict only if all of them clash with existing or repeated ids or registrations
+				code := http.StatusConflict
+				items := make([]BatchItemErrorJSON, len(batchErr.Items))
+				for i, item := range batchErr.Items {
+					items[i] = BatchItemErrorJSON{Index: item.Index, ID: item.Id}
+					var validationErr *internal.VehicleValidationError
+					if errors.As(item.Err, &validationErr) {
+						items[i].Message = "Datos del vehículo no válidos."
+						items[i].Errors = fieldErrorsJSON(validationErr)
+						code = http.StatusBadRequest
+						continue
+					}
+					switch item.Err {
+					case internal.ErrVehicleAlreadyExists:
+						items[i].Message = "Identificador del vehículo ya existente."
+					case internal.ErrVehicleDuplicatedInBatch:
+						items[i].Message = "Identificador del vehículo repetido en el lote."
+					case internal.ErrVehicleRegistrationAlreadyExists:
+						items[i].Message = "Matrícula del vehículo ya existente."
+					case internal.ErrVehicleRegistrationDuplicatedInBatch:
+						items[i].Message = "Matrícula del vehículo repetida en el lote."
+					case internal.ErrVehicleMandatoryFields:
+						items[i].Message = "Datos del vehículo mal formados o incompletos."
+						code = http.StatusBadRequest
+					default:
+						items[i].Message = "Datos del vehículo no válidos."
+						code = http.StatusBadRequest
+					}
+				}
+				response.JSON(w, code, map[string]any{
+					"status":  http.StatusText(code),
+					"message": "Ningún vehículo fue creado, algunos vehículos no son válidos.",
+					"errors":  items,
+				})
+				return
+			}
 			switch err {
 			case internal.ErrVehicleAlreadyExists:
 				response.Error(w, http.StatusConflict, "Algún vehículo tiene un identificador ya existente.")
@@ -186,19 +498,26 @@ func (h *VehicleDefault) BatchCreate() http.HandlerFunc {
 				response.Error(w, http.StatusBadRequest, "Datos de algún vehículo mal formados o incompletos.")
 				return
 			default:
-				response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+				serverError(w, err)
 				return
 			}
 		}
 
 		// response
+		// - the ids may have been allocated by the repository
+		data := make([]VehicleJSON, len(vehicles))
+		for i, v := range vehicles {
+			data[i] = vehicleJSON(*v)
+		}
 		response.JSON(w, http.StatusCreated, map[string]any{
 			"message": "Vehículos creados exitosamente.",
+			"data":    data,
 		})
 	}
 }
 
 // GetByColorAndYear is a method that returns a handler for the route GET /vehicles/color{color}/year/{year}
+// - sort, limit, offset and fields respond with a page of the matching vehicles, as GetAll
 func (h *VehicleDefault) GetByColorAndYear() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -210,17 +529,22 @@ func (h *VehicleDefault) GetByColorAndYear() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "Año mal formado.")
 			return
 		}
+		// - sort, limit, offset or fields respond with a page instead of the map
+		if isPageRequest(r.URL.Query()) {
+			h.list(w, r, eq("color", color), eq("year", year))
+			return
+		}
 
 		// process
 		// call the service to get the vehicles
-		v, err := h.sv.FindByColorAndYear(color, year)
+		v, err := h.sv.FindByColorAndYear(r.Context(), color, year)
 		if err != nil {
 			switch err {
 			case internal.ErrVehiclesNotFound:
 				response.Error(w, http.StatusNotFound, "No se encontraron vehículos con esos criterios.")
 				return
 			default:
-				response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+				serverError(w, err)
 				return
 			}
 		}
@@ -228,22 +552,7 @@ func (h *VehicleDefault) GetByColorAndYear() http.HandlerFunc {
 		// response
 		data := make(map[int]VehicleJSON)
 		for key, value := range v {
-			data[key] = VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			}
+			data[key] = vehicleJSON(value)
 		}
 		response.JSON(w, http.StatusOK, map[string]any{
 			"message": "Vehículos encontrados exitosamente.",
@@ -266,14 +575,14 @@ func (h *VehicleDefault) Delete() http.HandlerFunc {
 
 		// process
 		// - call the service to delete the vehicle by id
-		err = h.sv.Delete(id)
+		err = h.sv.Delete(r.Context(), id)
 		if err != nil {
 			switch err {
 			case internal.ErrVehicleNotFound:
 				response.Error(w, http.StatusNotFound, "No se encontró el vehículo con ese identificador.")
 				return
 			default:
-				response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+				serverError(w, err)
 				return
 			}
 		}
@@ -303,16 +612,26 @@ func (h *VehicleDefault) UpdateFuelType() http.HandlerFunc {
 			return
 		}
 
+		// - normalize the fuel type, accepting any case and the known aliases
+		fuelType, err := internal.ParseFuelType(reqBody.FuelType)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Tipo de combustible mal formado o no admitido.")
+			return
+		}
+
 		// process
 		// - call the service to update the fuel type of the vehicle by id
-		err = h.sv.UpdateFuelType(id, reqBody.FuelType)
+		err = h.sv.UpdateFuelType(r.Context(), id, fuelType)
 		if err != nil {
 			switch err {
 			case internal.ErrVehicleNotFound:
 				response.Error(w, http.StatusNotFound, "No se encontró el vehículo con ese identificador.")
 				return
+			case internal.ErrVehicleInvalidFuelType:
+				response.Error(w, http.StatusBadRequest, "Tipo de combustible mal formado o no admitido.")
+				return
 			default:
-				response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+				serverError(w, err)
 				return
 			}
 		}
@@ -324,7 +643,52 @@ func (h *VehicleDefault) UpdateFuelType() http.HandlerFunc {
 	}
 }
 
+// UpdateMaxSpeed is a method that returns a handler for the route PUT /vehicles/{id}/update_speed
+func (h *VehicleDefault) UpdateMaxSpeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from URL using chi
+		idString := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idString)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Identificador mal formado.")
+			return
+		}
+
+		// - get max speed from request body
+		var reqBody UpdateMaxSpeedJSON
+		err = json.NewDecoder(r.Body).Decode(&reqBody)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Velocidad mal formada o fuera de rango.")
+			return
+		}
+
+		// process
+		// - call the service to update the max speed of the vehicle by id
+		err = h.sv.UpdateMaxSpeed(r.Context(), id, reqBody.MaxSpeed)
+		if err != nil {
+			switch err {
+			case internal.ErrVehicleNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontró el vehículo con ese identificador.")
+				return
+			case internal.ErrVehicleInvalidMaxSpeed:
+				response.Error(w, http.StatusBadRequest, "Velocidad mal formada o fuera de rango.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Velocidad del vehículo actualizada exitosamente.",
+		})
+	}
+}
+
 // GetByWeightRange is a method that returns a handler for the route GET /vehicles/weight?min={weight_min}&max={weight_max}
+// - sort, limit, offset and fields respond with a page of the matching vehicles, as GetAll
 func (h *VehicleDefault) GetByWeightRange() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -343,17 +707,22 @@ func (h *VehicleDefault) GetByWeightRange() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "Peso máximo mal formado.")
 			return
 		}
+		// - sort, limit, offset or fields respond with a page instead of the map
+		if isPageRequest(r.URL.Query()) {
+			h.list(w, r, between("weight", min, max)...)
+			return
+		}
 
 		// process
 		// - get vehicles by weight range
-		v, err := h.sv.FindByWeightRange(min, max)
+		v, err := h.sv.FindByWeightRange(r.Context(), min, max)
 		if err != nil {
 			switch err {
 			case internal.ErrVehiclesNotFound:
 				response.Error(w, http.StatusNotFound, "No se encontraron vehículos con esos criterios.")
 				return
 			default:
-				response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+				serverError(w, err)
 				return
 			}
 		}
@@ -361,22 +730,7 @@ func (h *VehicleDefault) GetByWeightRange() http.HandlerFunc {
 		// response
 		data := make(map[int]VehicleJSON)
 		for key, value := range v {
-			data[key] = VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			}
+			data[key] = vehicleJSON(value)
 		}
 		response.JSON(w, http.StatusOK, map[string]any{
 			"message": "Vehículos encontrados exitosamente.",
@@ -386,6 +740,7 @@ func (h *VehicleDefault) GetByWeightRange() http.HandlerFunc {
 }
 
 // GetByBrandAndYearRange is a method that returns a handler for the route GET /vehicles/brand/{brand}/between/{start_year}/{end_year}
+// - sort, limit, offset and fields respond with a page of the matching vehicles, as GetAll
 func (h *VehicleDefault) GetByBrandAndYearRange() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
@@ -404,10 +759,15 @@ func (h *VehicleDefault) GetByBrandAndYearRange() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "Año de fin mal formado.")
 			return
 		}
+		// - sort, limit, offset or fields respond with a page instead of the map
+		if isPageRequest(r.URL.Query()) {
+			h.list(w, r, append(between("year", startYear, endYear), eq("brand", brand))...)
+			return
+		}
 
 		// process
 		// call the service method to get the vehicles by brand and year range
-		v, err := h.sv.FindByBrandAndYearRange(brand, startYear, endYear)
+		v, err := h.sv.FindByBrandAndYearRange(r.Context(), brand, startYear, endYear)
 
 		if err != nil {
 			switch err {
@@ -415,7 +775,7 @@ func (h *VehicleDefault) GetByBrandAndYearRange() http.HandlerFunc {
 				response.Error(w, http.StatusNotFound, "No se encontraron vehículos con esos criterios.")
 				return
 			default:
-				response.Error(w, http.StatusInternalServerError, "Algo ha salido mal.")
+				serverError(w, err)
 				return
 			}
 		}
@@ -423,28 +783,396 @@ func (h *VehicleDefault) GetByBrandAndYearRange() http.HandlerFunc {
 		// serialize
 		var data []VehicleJSON
 		for _, value := range v {
-			data = append(data, VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
-			})
+			data = append(data, vehicleJSON(value))
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículos encontrados exitosamente.",
+			"data":    data,
+		})
+	}
+}
+
+// GetAverageMaxSpeedByBrand is a method that returns a handler for the route GET /vehicles/average_speed/brand/{brand}
+func (h *VehicleDefault) GetAverageMaxSpeedByBrand() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get brand from URL using chi
+		brand := chi.URLParam(r, "brand")
+
+		// process
+		// - call the service to get the average max speed of the brand
+		avg, err := h.sv.FindAverageMaxSpeedByBrand(r.Context(), brand)
+		if err != nil {
+			switch err {
+			case internal.ErrVehiclesNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontraron vehículos de esa marca.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Velocidad promedio de la marca obtenida exitosamente.",
+			"data":    avg,
+		})
+	}
+}
+
+// GetByFuelType is a method that returns a handler for the route GET /vehicles/fuel_type/{type}
+// - sort, limit, offset and fields respond with a page of the matching vehicles, as GetAll
+func (h *VehicleDefault) GetByFuelType() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get fuel type from URL using chi, resolving its aliases
+		fuelType := string(internal.NormalizeFuelType(chi.URLParam(r, "type")))
+		// - sort, limit, offset or fields respond with a page instead of the map
+		if isPageRequest(r.URL.Query()) {
+			h.list(w, r, eq("fuel_type", fuelType))
+			return
+		}
+
+		// process
+		// - call the service to get the vehicles by fuel type
+		v, err := h.sv.FindByFuelType(r.Context(), fuelType)
+		if err != nil {
+			switch err {
+			case internal.ErrVehiclesNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontraron vehículos con esos criterios.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
 		}
 
 		// response
+		data := make(map[int]VehicleJSON)
+		for key, value := range v {
+			data[key] = vehicleJSON(value)
+		}
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículos encontrados exitosamente.",
+			"data":    data,
+		})
+	}
+}
+
+// GetByTransmission is a method that returns a handler for the route GET /vehicles/transmission/{type}
+// - sort, limit, offset and fields respond with a page of the matching vehicles, as GetAll
+func (h *VehicleDefault) GetByTransmission() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get transmission from URL using chi, resolving its aliases
+		transmission := string(internal.NormalizeTransmission(chi.URLParam(r, "type")))
+		// - sort, limit, offset or fields respond with a page instead of the map
+		if isPageRequest(r.URL.Query()) {
+			h.list(w, r, eq("transmission", transmission))
+			return
+		}
+
+		// process
+		// - call the service to get the vehicles by transmission
+		v, err := h.sv.FindByTransmission(r.Context(), transmission)
+		if err != nil {
+			switch err {
+			case internal.ErrVehiclesNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontraron vehículos con esos criterios.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		data := make(map[int]VehicleJSON)
+		for key, value := range v {
+			data[key] = vehicleJSON(value)
+		}
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículos encontrados exitosamente.",
+			"data":    data,
+		})
+	}
+}
+
+// GetAverageCapacityByBrand is a method that returns a handler for the route GET /vehicles/average_capacity/brand/{brand}
+func (h *VehicleDefault) GetAverageCapacityByBrand() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get brand from URL using chi
+		brand := chi.URLParam(r, "brand")
+
+		// process
+		// - call the service to get the average capacity of the brand
+		avg, err := h.sv.FindAverageCapacityByBrand(r.Context(), brand)
+		if err != nil {
+			switch err {
+			case internal.ErrVehiclesNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontraron vehículos de esa marca.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Capacidad promedio de la marca obtenida exitosamente.",
+			"data":    avg,
+		})
+	}
+}
+
+// GetByDimensions is a method that returns a handler for the route GET /vehicles/dimensions?length={min_length}-{max_length}&width={min_width}-{max_width}
+// - sort, limit, offset and fields respond with a page of the matching vehicles, as GetAll
+func (h *VehicleDefault) GetByDimensions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get length and width ranges from query params
+		minLength, maxLength, err := parseRange(r.URL.Query().Get("length"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Rango de largo mal formado.")
+			return
+		}
+		minWidth, maxWidth, err := parseRange(r.URL.Query().Get("width"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Rango de ancho mal formado.")
+			return
+		}
+		// - sort, limit, offset or fields respond with a page instead of the map
+		if isPageRequest(r.URL.Query()) {
+			h.list(w, r, append(between("length", minLength, maxLength), between("width", minWidth, maxWidth)...)...)
+			return
+		}
+
+		// process
+		// - get vehicles by dimensions
+		v, err := h.sv.FindByDimensions(r.Context(), minLength, maxLength, minWidth, maxWidth)
+		if err != nil {
+			switch err {
+			case internal.ErrVehiclesNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontraron vehículos con esos criterios.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		data := make(map[int]VehicleJSON)
+		for key, value := range v {
+			data[key] = vehicleJSON(value)
+		}
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículos encontrados exitosamente.",
+			"data":    data,
+		})
+	}
+}
+
+// Search is a method that returns a handler for the route GET /vehicles/search?{field}[{operator}]={value}
+// - sort, limit, offset and fields respond with a page of the matching vehicles, as GetAll
+func (h *VehicleDefault) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get filters from query params
+		q, err := internal.ParseVehicleQuery(r.URL.Query())
+		if err != nil {
+			queryError(w, err)
+			return
+		}
+		if isPageRequest(r.URL.Query()) {
+			fields, err := internal.ParseVehicleFields(r.URL.Query().Get(internal.QueryParamFields))
+			if err != nil {
+				queryError(w, err)
+				return
+			}
+			h.findPage(w, r, "Vehículos encontrados exitosamente.", q, fields)
+			return
+		}
+
+		// process
+		// - get vehicles matching the filters
+		v, err := h.sv.Find(r.Context(), q)
+		if err != nil {
+			var queryErr *internal.VehicleQueryError
+			switch {
+			case errors.As(err, &queryErr):
+				queryError(w, err)
+				return
+			case err == internal.ErrVehiclesNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontraron vehículos con esos criterios.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		data := make(map[int]VehicleJSON)
+		for key, value := range v {
+			data[key] = vehicleJSON(value)
+		}
 		response.JSON(w, http.StatusOK, map[string]any{
 			"message": "Vehículos encontrados exitosamente.",
 			"data":    data,
 		})
 	}
 }
+
+// Update is a method that returns a handler for the route PUT /vehicles/{id}
+// - the body replaces every attribute of the vehicle, its id may be omitted
+func (h *VehicleDefault) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from URL using chi
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Identificador mal formado.")
+			return
+		}
+
+		// - get vehicle from request body
+		var reqBody VehicleJSON
+		err = json.NewDecoder(r.Body).Decode(&reqBody)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Datos del vehículo mal formados o incompletos.")
+			return
+		}
+		if reqBody.ID != 0 && reqBody.ID != id {
+			response.Error(w, http.StatusBadRequest, "El identificador del vehículo no puede modificarse.")
+			return
+		}
+		reqBody.ID = id
+
+		// process
+		// - call the service to replace the vehicle
+		vehicle := reqBody.vehicle()
+		err = h.sv.Update(r.Context(), vehicle)
+		if err != nil {
+			var validationErr *internal.VehicleValidationError
+			if errors.As(err, &validationErr) {
+				validationError(w, validationErr)
+				return
+			}
+			switch err {
+			case internal.ErrVehicleNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontró el vehículo con ese identificador.")
+				return
+			case internal.ErrVehicleMandatoryFields:
+				response.Error(w, http.StatusBadRequest, "Datos del vehículo mal formados o incompletos.")
+				return
+			case internal.ErrVehicleInvalidMaxSpeed:
+				response.Error(w, http.StatusBadRequest, "Velocidad mal formada o fuera de rango.")
+				return
+			case internal.ErrVehicleRegistrationAlreadyExists:
+				response.Error(w, http.StatusConflict, "Matrícula del vehículo ya existente.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículo actualizado exitosamente.",
+			"data":    vehicleJSON(*vehicle),
+		})
+	}
+}
+
+// Patch is a method that returns a handler for the route PATCH /vehicles/{id}
+// - the body is a JSON Merge Patch (RFC 7396) over VehicleJSON, every attribute is required so none can be removed with null
+func (h *VehicleDefault) Patch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get id from URL using chi
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Identificador mal formado.")
+			return
+		}
+
+		// - get merge patch from request body
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Datos del vehículo mal formados o incompletos.")
+			return
+		}
+		var members map[string]json.RawMessage
+		err = json.Unmarshal(body, &members)
+		if err != nil || members == nil {
+			response.Error(w, http.StatusBadRequest, "Datos del vehículo mal formados o incompletos.")
+			return
+		}
+		for field, value := range members {
+			if string(value) == "null" {
+				response.Errorf(w, http.StatusBadRequest, "El campo %s no puede eliminarse.", field)
+				return
+			}
+		}
+		// - check the types of the members before touching the vehicle
+		var probe VehicleJSON
+		err = json.Unmarshal(body, &probe)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Datos del vehículo mal formados o incompletos.")
+			return
+		}
+		if _, ok := members["id"]; ok && probe.ID != id {
+			response.Error(w, http.StatusBadRequest, "El identificador del vehículo no puede modificarse.")
+			return
+		}
+
+		// process
+		// - call the service to merge the patch into the vehicle
+		v, err := h.sv.Patch(r.Context(), id, func(v *internal.Vehicle) (err error) {
+			merged := vehicleJSON(*v)
+			err = json.Unmarshal(body, &merged)
+			if err != nil {
+				return
+			}
+			*v = *merged.vehicle()
+			return
+		})
+		if err != nil {
+			var validationErr *internal.VehicleValidationError
+			if errors.As(err, &validationErr) {
+				validationError(w, validationErr)
+				return
+			}
+			switch err {
+			case internal.ErrVehicleNotFound:
+				response.Error(w, http.StatusNotFound, "No se encontró el vehículo con ese identificador.")
+				return
+			case internal.ErrVehicleMandatoryFields:
+				response.Error(w, http.StatusBadRequest, "Datos del vehículo mal formados o incompletos.")
+				return
+			case internal.ErrVehicleInvalidMaxSpeed:
+				response.Error(w, http.StatusBadRequest, "Velocidad mal formada o fuera de rango.")
+				return
+			case internal.ErrVehicleRegistrationAlreadyExists:
+				response.Error(w, http.StatusConflict, "Matrícula del vehículo ya existente.")
+				return
+			default:
+				serverError(w, err)
+				return
+			}
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "Vehículo actualizado exitosamente.",
+			"data":    vehicleJSON(v),
+		})
+	}
+}
@@ -3,6 +3,7 @@ package handler_test
 import (
 	"app/internal"
 	"app/internal/handler"
+	"app/internal/repository"
 	"app/internal/service"
 	"context"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/mock"
@@ -59,7 +61,7 @@ func TestCreate(t *testing.T) {
 	t.Run("should return status code 201 when vehicle is created", func(t *testing.T) {
 		// ARRANGE
 		// - vehicle
-		vehicle := internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77)
+		vehicle := internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, internal.FuelTypeGasoline, internal.TransmissionAutomatic, 1300.0, 1.45, 4.62, 1.77)
 
 		// - service mock
 		service := new(service.VehicleDefaultMock)
@@ -103,4 +105,1034 @@ func TestCreate(t *testing.T) {
 		require.Equal(t, http.StatusCreated, w.Code)
 	})
 
+	t.Run("should return status code 201 with the location of the vehicle when the id is allocated", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior: allocate the id as the repository would
+		service.On("Create", mock.MatchedBy(func(v *internal.Vehicle) bool {
+			return v.Id == 0
+		})).Run(func(args mock.Arguments) {
+			args.Get(0).(*internal.Vehicle).Id = 101
+		}).Return(nil)
+
+		// - request
+		reqBody := `{"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"gasoline","transmission":"automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}`
+		req := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(reqBody))
+		// - response recorder
+		w := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Create())
+
+		// ACT
+		reqHandler.ServeHTTP(w, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "/vehicles/101", w.Header().Get("Location"))
+		expectedBody := `{"message":"Vehículo creado exitosamente.","data":{"id":101,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"gasoline","transmission":"automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}}`
+		require.JSONEq(t, expectedBody, w.Body.String())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 400 listing every rule broken by the vehicle", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("Create", mock.Anything).Return(&internal.VehicleValidationError{Fields: []internal.VehicleFieldError{
+			{Field: "brand", Rule: internal.RuleRequired},
+			{Field: "year", Rule: internal.RuleMin, Limit: 1886},
+			{Field: "fuel_type", Rule: internal.RuleOneOf, Allowed: []string{"diesel", "electric"}},
+		}})
+
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Create())
+
+		// - request
+		req := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(`{"id": 1001, "year": 1800, "fuel_type": "coal"}`))
+		// - response recorder
+		w := httptest.NewRecorder()
+
+		// ACT
+		reqHandler.ServeHTTP(w, req)
+
+		// ASSERT
+		// - status code
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		// - body
+		expectedBody := `{
+			"status": "Bad Request",
+			"message": "Datos del vehículo no válidos.",
+			"errors": [
+				{"field": "brand", "message": "Campo obligatorio."},
+				{"field": "year", "message": "Debe ser mayor o igual que 1886."},
+				{"field": "fuel_type", "message": "Debe ser uno de: diesel, electric."}
+			]
+		}`
+		require.JSONEq(t, expectedBody, w.Body.String())
+	})
+}
+
+func TestGetAverageMaxSpeedByBrand(t *testing.T) {
+	t.Run("should return status code 200 with the average max speed of the brand", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindAverageMaxSpeedByBrand", "Toyota").Return(150.5, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/Toyota", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("brand", "Toyota")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetAverageMaxSpeedByBrand())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"message":"Velocidad promedio de la marca obtenida exitosamente.","data":150.5}`, rr.Body.String())
+	})
+
+	t.Run("should return status code 404 when the brand has no vehicles", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindAverageMaxSpeedByBrand", "Unknown").Return(0.0, internal.ErrVehiclesNotFound)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/average_speed/brand/Unknown", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("brand", "Unknown")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetAverageMaxSpeedByBrand())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestGetAverageCapacityByBrand(t *testing.T) {
+	t.Run("should return status code 200 with the average capacity of the brand", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindAverageCapacityByBrand", "Toyota").Return(4.5, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/average_capacity/brand/Toyota", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("brand", "Toyota")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetAverageCapacityByBrand())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"message":"Capacidad promedio de la marca obtenida exitosamente.","data":4.5}`, rr.Body.String())
+	})
+
+	t.Run("should return status code 404 when the brand has no vehicles", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindAverageCapacityByBrand", "Unknown").Return(0.0, internal.ErrVehiclesNotFound)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/average_capacity/brand/Unknown", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("brand", "Unknown")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetAverageCapacityByBrand())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.JSONEq(t, `{"status":"Not Found","message":"No se encontraron vehículos de esa marca."}`, rr.Body.String())
+	})
+}
+
+func TestUpdateMaxSpeed(t *testing.T) {
+	t.Run("should return status code 400 when the max speed is out of range", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("UpdateMaxSpeed", 1, -10.0).Return(internal.ErrVehicleInvalidMaxSpeed)
+
+		// - request
+		req := httptest.NewRequest(http.MethodPut, "/vehicles/1/update_speed", strings.NewReader(`{"max_speed": -10}`))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.UpdateMaxSpeed())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		service.AssertExpectations(t)
+	})
+}
+
+func TestUpdateFuelType(t *testing.T) {
+	t.Run("should return status code 200 storing the canonical fuel type of an alias", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("UpdateFuelType", 1, internal.FuelTypeGasoline).Return(nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodPut, "/vehicles/1/fuel-type", strings.NewReader(`{"fuel_type": " Petrol "}`))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.UpdateFuelType())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 400 when the fuel type is not supported", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+
+		// - request
+		req := httptest.NewRequest(http.MethodPut, "/vehicles/1/fuel-type", strings.NewReader(`{"fuel_type": "coal"}`))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.UpdateFuelType())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, `{"status":"Bad Request","message":"Tipo de combustible mal formado o no admitido."}`, rr.Body.String())
+		service.AssertNotCalled(t, "UpdateFuelType", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetByDimensions(t *testing.T) {
+	t.Run("should return status code 400 when the length range is malformed", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/dimensions?length=abc&width=1-2", nil)
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetByDimensions())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, `{"status":"Bad Request","message":"Rango de largo mal formado."}`, rr.Body.String())
+		service.AssertNotCalled(t, "FindByDimensions")
+	})
+
+	t.Run("should return status code 200 when vehicles are found", func(t *testing.T) {
+		// ARRANGE
+		// - vehicles
+		vehicles := map[int]internal.Vehicle{
+			1001: *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77),
+		}
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindByDimensions", 4.0, 5.0, 1.5, 2.0).Return(vehicles, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/dimensions?length=4-5&width=1.5-2", nil)
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetByDimensions())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		service.AssertExpectations(t)
+	})
+}
+
+func TestGetByFuelType(t *testing.T) {
+	t.Run("should return status code 200 with the vehicles of the fuel type", func(t *testing.T) {
+		// ARRANGE
+		// - vehicles
+		vehicles := map[int]internal.Vehicle{
+			1001: *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77),
+		}
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindByFuelType", "gasoline").Return(vehicles, nil)
+
+		// - request, with an alias of the fuel type
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/fuel_type/petrol", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("type", "petrol")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetByFuelType())
+
+		// - expected response
+		expectedResponse := `{"message":"Vehículos encontrados exitosamente.","data":{"1001":{"id":1001,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"gasoline","transmission":"automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}}}`
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 404 when no vehicle has the fuel type", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindByFuelType", "electric").Return(map[int]internal.Vehicle{}, internal.ErrVehiclesNotFound)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/fuel_type/electric", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("type", "electric")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetByFuelType())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.JSONEq(t, `{"status":"Not Found","message":"No se encontraron vehículos con esos criterios."}`, rr.Body.String())
+	})
+}
+
+func TestGetByTransmission(t *testing.T) {
+	t.Run("should return status code 200 with the vehicles of the transmission", func(t *testing.T) {
+		// ARRANGE
+		// - vehicles
+		vehicles := map[int]internal.Vehicle{
+			1001: *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77),
+		}
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindByTransmission", "automatic").Return(vehicles, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/transmission/automatic", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("type", "automatic")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetByTransmission())
+
+		// - expected response
+		expectedResponse := `{"message":"Vehículos encontrados exitosamente.","data":{"1001":{"id":1001,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"gasoline","transmission":"automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}}}`
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 404 when no vehicle has the transmission", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindByTransmission", "manual").Return(map[int]internal.Vehicle{}, internal.ErrVehiclesNotFound)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/transmission/manual", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("type", "manual")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetByTransmission())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.JSONEq(t, `{"status":"Not Found","message":"No se encontraron vehículos con esos criterios."}`, rr.Body.String())
+	})
+}
+
+func TestBatchCreate(t *testing.T) {
+	t.Run("should return status code 409 listing every vehicle that already exists", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("BatchCreate", mock.Anything).Return(&internal.VehicleBatchError{
+			Items: []internal.VehicleBatchItemError{
+				{Index: 0, Id: 1, Err: internal.ErrVehicleAlreadyExists},
+				{Index: 2, Id: 1, Err: internal.ErrVehicleDuplicatedInBatch},
+			},
+		})
+		// - expected response
+		expectedResponse := `{"status":"Conflict","message":"Ningún vehículo fue creado, algunos vehículos no son válidos.","errors":[{"index":0,"id":1,"message":"Identificador del vehículo ya existente."},{"index":2,"id":1,"message":"Identificador del vehículo repetido en el lote."}]}`
+
+		// - request
+		req := httptest.NewRequest(http.MethodPost, "/vehicles/batch", strings.NewReader(`{"vehicles":[{"id":1},{"id":2},{"id":1}]}`))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.BatchCreate())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+	})
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("should return status code 200 with the vehicles matching the filters", func(t *testing.T) {
+		// ARRANGE
+		// - vehicles
+		vehicles := map[int]internal.Vehicle{
+			1001: *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77),
+		}
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("Find", internal.VehicleQuery{Filters: []internal.VehicleFilter{
+			{Field: "year", Operator: internal.OpGte, Values: []any{2020}},
+		}}).Return(vehicles, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/search?year[gte]=2020", nil)
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Search())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should normalize the aliases of the fuel type and the transmission as their routes do", func(t *testing.T) {
+		// ARRANGE
+		// - vehicles
+		vehicles := map[int]internal.Vehicle{
+			1001: *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, internal.FuelTypeGasoline, internal.TransmissionAutomatic, 1300.0, 1.45, 4.62, 1.77),
+		}
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("Find", internal.VehicleQuery{Filters: []internal.VehicleFilter{
+			{Field: "fuel_type", Operator: internal.OpEq, Values: []any{"gasoline"}},
+			{Field: "transmission", Operator: internal.OpIn, Values: []any{"automatic", "manual"}},
+		}}).Return(vehicles, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/search?fuel_type=Petrol&transmission[in]=auto,stick", nil)
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Search())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 400 with a precise message when the filters are invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"/vehicles/search?owner=Juan":           `{"status":"Bad Request","message":"Campo desconocido: owner."}`,
+			"/vehicles/search?year[contains]=20":    `{"status":"Bad Request","message":"Operador no admitido para el campo year: contains."}`,
+			"/vehicles/search?weight[gte]=heavy":    `{"status":"Bad Request","message":"Valor mal formado para el campo weight: heavy."}`,
+			"/vehicles/search?passengers[in]=2,x,4": `{"status":"Bad Request","message":"Valor mal formado para el campo passengers: x."}`,
+			// - parameters are checked in alphabetical order
+			"/vehicles/search?owner=Juan&color[gt]=red&age=3": `{"status":"Bad Request","message":"Campo desconocido: age."}`,
+		}
+		for url, expectedResponse := range cases {
+			// ARRANGE
+			// - service mock
+			service := new(service.VehicleDefaultMock)
+
+			// - request
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			// - response recorder
+			rr := httptest.NewRecorder()
+			// - handler
+			h := handler.NewVehicleDefault(service)
+			reqHandler := http.HandlerFunc(h.Search())
+
+			// ACT
+			reqHandler.ServeHTTP(rr, req)
+
+			// ASSERT
+			require.Equal(t, http.StatusBadRequest, rr.Code, url)
+			require.JSONEq(t, expectedResponse, rr.Body.String(), url)
+			service.AssertNotCalled(t, "Find", mock.Anything)
+		}
+	})
+}
+
+func TestGetAll(t *testing.T) {
+	t.Run("should return status code 200 with a page of the vehicles when pagination is requested", func(t *testing.T) {
+		// ARRANGE
+		// - page
+		page := internal.VehiclePage{
+			Vehicles: []internal.Vehicle{
+				*internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77),
+			},
+			Total: 3,
+		}
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindPage", internal.VehicleQuery{
+			Sort:  []internal.VehicleSort{{Field: "year", Desc: true}, {Field: "brand"}},
+			Limit: 1,
+		}).Return(page, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles?sort=-year,brand&limit=1&fields=id,brand,max_speed", nil)
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetAll())
+
+		// - expected response
+		expectedResponse := `{"message":"success","data":[{"id":1001,"brand":"Toyota","max_speed":180}],"meta":{"total":3,"limit":1,"offset":0}}`
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 400 when the pagination is invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"/vehicles?limit=0":         `{"status":"Bad Request","message":"Valor mal formado para el campo limit: 0."}`,
+			"/vehicles?offset=-1":       `{"status":"Bad Request","message":"Valor mal formado para el campo offset: -1."}`,
+			"/vehicles?sort=-owner":     `{"status":"Bad Request","message":"Campo desconocido: owner."}`,
+			"/vehicles?fields=id,plate": `{"status":"Bad Request","message":"Campo desconocido: plate."}`,
+		}
+		for url, expectedResponse := range cases {
+			// ARRANGE
+			// - service mock
+			service := new(service.VehicleDefaultMock)
+
+			// - request
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			// - response recorder
+			rr := httptest.NewRecorder()
+			// - handler
+			h := handler.NewVehicleDefault(service)
+			reqHandler := http.HandlerFunc(h.GetAll())
+
+			// ACT
+			reqHandler.ServeHTTP(rr, req)
+
+			// ASSERT
+			require.Equal(t, http.StatusBadRequest, rr.Code, url)
+			require.JSONEq(t, expectedResponse, rr.Body.String(), url)
+			service.AssertNotCalled(t, "FindPage", mock.Anything)
+		}
+	})
+
+	t.Run("should return status code 504 or 503 when the request times out or is cancelled", func(t *testing.T) {
+		expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancelExpired()
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		cases := map[string]struct {
+			ctx      context.Context
+			code     int
+			response string
+		}{
+			"expired":   {expired, http.StatusGatewayTimeout, `{"status":"Gateway Timeout","message":"Tiempo de espera agotado."}`},
+			"cancelled": {cancelled, http.StatusServiceUnavailable, `{"status":"Service Unavailable","message":"Servicio no disponible."}`},
+		}
+		for name, c := range cases {
+			// ARRANGE
+			// - service over a repository that gives up once the context is done
+			sv := service.NewVehicleDefault(repository.NewVehicleMap(map[int]internal.Vehicle{
+				1001: *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77),
+			}))
+
+			// - request
+			req := httptest.NewRequest(http.MethodGet, "/vehicles", nil).WithContext(c.ctx)
+			// - response recorder
+			rr := httptest.NewRecorder()
+			// - handler
+			h := handler.NewVehicleDefault(sv)
+			reqHandler := http.HandlerFunc(h.GetAll())
+
+			// ACT
+			reqHandler.ServeHTTP(rr, req)
+
+			// ASSERT
+			require.Equal(t, c.code, rr.Code, name)
+			require.JSONEq(t, c.response, rr.Body.String(), name)
+		}
+	})
+}
+
+func TestListRoutesPagination(t *testing.T) {
+	t.Run("should return status code 200 with a page of the vehicles when pagination is requested", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			handler func(h *handler.VehicleDefault) http.HandlerFunc
+			url     string
+			params  map[string]string
+			filters []internal.VehicleFilter
+		}{
+			{
+				name:    "color and year",
+				handler: (*handler.VehicleDefault).GetByColorAndYear,
+				url:     "/vehicles/color/Blue/year/2020?limit=1",
+				params:  map[string]string{"color": "Blue", "year": "2020"},
+				filters: []internal.VehicleFilter{
+					{Field: "color", Operator: internal.OpEq, Values: []any{"Blue"}},
+					{Field: "year", Operator: internal.OpEq, Values: []any{2020}},
+				},
+			},
+			{
+				name:    "weight range",
+				handler: (*handler.VehicleDefault).GetByWeightRange,
+				url:     "/vehicles/weight?min=1000&max=1500&limit=1",
+				filters: []internal.VehicleFilter{
+					{Field: "weight", Operator: internal.OpGte, Values: []any{1000.0}},
+					{Field: "weight", Operator: internal.OpLte, Values: []any{1500.0}},
+				},
+			},
+			{
+				name:    "brand and year range",
+				handler: (*handler.VehicleDefault).GetByBrandAndYearRange,
+				url:     "/vehicles/brand/Toyota/between/2015/2020?limit=1",
+				params:  map[string]string{"brand": "Toyota", "start_year": "2015", "end_year": "2020"},
+				filters: []internal.VehicleFilter{
+					{Field: "year", Operator: internal.OpGte, Values: []any{2015}},
+					{Field: "year", Operator: internal.OpLte, Values: []any{2020}},
+					{Field: "brand", Operator: internal.OpEq, Values: []any{"Toyota"}},
+				},
+			},
+			{
+				name:    "fuel type",
+				handler: (*handler.VehicleDefault).GetByFuelType,
+				url:     "/vehicles/fuel_type/petrol?limit=1",
+				params:  map[string]string{"type": "petrol"},
+				filters: []internal.VehicleFilter{{Field: "fuel_type", Operator: internal.OpEq, Values: []any{"gasoline"}}},
+			},
+			{
+				name:    "transmission",
+				handler: (*handler.VehicleDefault).GetByTransmission,
+				url:     "/vehicles/transmission/automatic?limit=1",
+				params:  map[string]string{"type": "automatic"},
+				filters: []internal.VehicleFilter{{Field: "transmission", Operator: internal.OpEq, Values: []any{"automatic"}}},
+			},
+			{
+				name:    "dimensions",
+				handler: (*handler.VehicleDefault).GetByDimensions,
+				url:     "/vehicles/dimensions?length=4-5&width=1.5-2&limit=1",
+				filters: []internal.VehicleFilter{
+					{Field: "length", Operator: internal.OpGte, Values: []any{4.0}},
+					{Field: "length", Operator: internal.OpLte, Values: []any{5.0}},
+					{Field: "width", Operator: internal.OpGte, Values: []any{1.5}},
+					{Field: "width", Operator: internal.OpLte, Values: []any{2.0}},
+				},
+			},
+		}
+		for _, c := range cases {
+			// ARRANGE
+			// - page
+			page := internal.VehiclePage{
+				Vehicles: []internal.Vehicle{
+					*internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77),
+				},
+				Total: 2,
+			}
+			// - service mock
+			service := new(service.VehicleDefaultMock)
+			// define mock behavior
+			service.On("FindPage", internal.VehicleQuery{
+				Filters: c.filters,
+				Sort:    []internal.VehicleSort{{Field: "max_speed", Desc: true}},
+				Limit:   1,
+			}).Return(page, nil)
+
+			// - request
+			req := httptest.NewRequest(http.MethodGet, c.url+"&sort=-max_speed&fields=id,brand", nil)
+			routeContext := chi.NewRouteContext()
+			for key, value := range c.params {
+				routeContext.URLParams.Add(key, value)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+			// - response recorder
+			rr := httptest.NewRecorder()
+			// - handler
+			h := handler.NewVehicleDefault(service)
+			reqHandler := c.handler(h)
+
+			// - expected response
+			expectedResponse := `{"message":"Vehículos encontrados exitosamente.","data":[{"id":1001,"brand":"Toyota"}],"meta":{"total":2,"limit":1,"offset":0}}`
+
+			// ACT
+			reqHandler.ServeHTTP(rr, req)
+
+			// ASSERT
+			require.Equal(t, http.StatusOK, rr.Code, c.name)
+			require.JSONEq(t, expectedResponse, rr.Body.String(), c.name)
+			service.AssertExpectations(t)
+		}
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("should return status code 200 and replace the vehicle of the route", func(t *testing.T) {
+		// ARRANGE
+		// - vehicle
+		vehicle := internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "White", 2021, 5, 185.0, internal.FuelTypeHybrid, internal.TransmissionAutomatic, 1350.0, 1.45, 4.62, 1.77)
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("Update", vehicle).Return(nil)
+
+		// - request
+		reqBody := `{"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"White","year":2021,"passengers":5,"max_speed":185,"fuel_type":"Hybrid","transmission":"Automatic","weight":1350,"height":1.45,"length":4.62,"width":1.77}`
+		req := httptest.NewRequest(http.MethodPut, "/vehicles/1001", strings.NewReader(reqBody))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1001")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Update())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 400 when the body changes the id", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+
+		// - request
+		req := httptest.NewRequest(http.MethodPut, "/vehicles/1001", strings.NewReader(`{"id":1002,"brand":"Toyota"}`))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1001")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Update())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, `{"status":"Bad Request","message":"El identificador del vehículo no puede modificarse."}`, rr.Body.String())
+		service.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("should return status code 200 with the stored vehicle when the body uses aliases", func(t *testing.T) {
+		// ARRANGE
+		// - vehicle, as stored
+		vehicle := internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "White", 2021, 5, 185.0, internal.FuelTypeGasoline, internal.TransmissionAutomatic, 1350.0, 1.45, 4.62, 1.77)
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("Update", vehicle).Return(nil)
+
+		// - request
+		reqBody := `{"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"White","year":2021,"passengers":5,"max_speed":185,"fuel_type":"petrol","transmission":"auto","weight":1350,"height":1.45,"length":4.62,"width":1.77}`
+		req := httptest.NewRequest(http.MethodPut, "/vehicles/1001", strings.NewReader(reqBody))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1001")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Update())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		expectedBody := `{"message":"Vehículo actualizado exitosamente.","data":{"id":1001,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"White","year":2021,"passengers":5,"max_speed":185,"fuel_type":"gasoline","transmission":"automatic","weight":1350,"height":1.45,"length":4.62,"width":1.77}}`
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedBody, rr.Body.String())
+		service.AssertExpectations(t)
+	})
+}
+
+func TestPatch(t *testing.T) {
+	t.Run("should return status code 200 with the vehicle after merging the patch", func(t *testing.T) {
+		// ARRANGE
+		// - vehicles
+		stored := *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77)
+		expected := *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Black", 2020, 5, 190.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77)
+		// - service mock
+		var merged internal.Vehicle
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior: apply the patch to the stored vehicle
+		service.On("Patch", 1001, mock.Anything).Run(func(args mock.Arguments) {
+			merged = stored
+			err := args.Get(1).(func(v *internal.Vehicle) error)(&merged)
+			require.NoError(t, err)
+		}).Return(expected, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodPatch, "/vehicles/1001", strings.NewReader(`{"color":"Black","max_speed":190}`))
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1001")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.Patch())
+
+		// - expected response
+		expectedResponse := `{"message":"Vehículo actualizado exitosamente.","data":{"id":1001,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Black","year":2020,"passengers":5,"max_speed":190,"fuel_type":"gasoline","transmission":"automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}}`
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+		require.Equal(t, expected, merged)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 400 when the patch is invalid", func(t *testing.T) {
+		cases := map[string]string{
+			`[{"color":"Black"}]`:       `{"status":"Bad Request","message":"Datos del vehículo mal formados o incompletos."}`,
+			`{"year":"2020"}`:           `{"status":"Bad Request","message":"Datos del vehículo mal formados o incompletos."}`,
+			`{"color":null}`:            `{"status":"Bad Request","message":"El campo color no puede eliminarse."}`,
+			`{"id":1002,"color":"Red"}`: `{"status":"Bad Request","message":"El identificador del vehículo no puede modificarse."}`,
+		}
+		for reqBody, expectedResponse := range cases {
+			// ARRANGE
+			// - service mock
+			service := new(service.VehicleDefaultMock)
+
+			// - request
+			req := httptest.NewRequest(http.MethodPatch, "/vehicles/1001", strings.NewReader(reqBody))
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", "1001")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+			// - response recorder
+			rr := httptest.NewRecorder()
+			// - handler
+			h := handler.NewVehicleDefault(service)
+			reqHandler := http.HandlerFunc(h.Patch())
+
+			// ACT
+			reqHandler.ServeHTTP(rr, req)
+
+			// ASSERT
+			require.Equal(t, http.StatusBadRequest, rr.Code, reqBody)
+			require.JSONEq(t, expectedResponse, rr.Body.String(), reqBody)
+			service.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func TestGetById(t *testing.T) {
+	t.Run("should return status code 200 with the vehicle", func(t *testing.T) {
+		// ARRANGE
+		// - vehicle
+		vehicle := *internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77)
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindById", 1001).Return(vehicle, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/1001", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("id", "1001")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetById())
+
+		// - expected response
+		expectedResponse := `{"message":"Vehículo encontrado exitosamente.","data":{"id":1001,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"Gasoline","transmission":"Automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}}`
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+	})
+
+	t.Run("should return status code 504 or 503 when the request times out or is cancelled", func(t *testing.T) {
+		cases := map[error]struct {
+			code     int
+			response string
+		}{
+			context.DeadlineExceeded: {http.StatusGatewayTimeout, `{"status":"Gateway Timeout","message":"Tiempo de espera agotado."}`},
+			context.Canceled:         {http.StatusServiceUnavailable, `{"status":"Service Unavailable","message":"Servicio no disponible."}`},
+		}
+		for err, c := range cases {
+			// ARRANGE
+			// - service mock
+			service := new(service.VehicleDefaultMock)
+			// define mock behavior
+			service.On("FindById", 1001).Return(internal.Vehicle{}, err)
+
+			// - request
+			req := httptest.NewRequest(http.MethodGet, "/vehicles/1001", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("id", "1001")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+			// - response recorder
+			rr := httptest.NewRecorder()
+			// - handler
+			h := handler.NewVehicleDefault(service)
+			reqHandler := http.HandlerFunc(h.GetById())
+
+			// ACT
+			reqHandler.ServeHTTP(rr, req)
+
+			// ASSERT
+			require.Equal(t, c.code, rr.Code)
+			require.JSONEq(t, c.response, rr.Body.String())
+		}
+	})
+}
+
+func TestGetByRegistration(t *testing.T) {
+	t.Run("should return status code 404 when no vehicle has the registration", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindByRegistration", "ZZZ-0000").Return(internal.Vehicle{}, internal.ErrVehicleNotFound)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/vehicles/registration/ZZZ-0000", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("registration", "ZZZ-0000")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleDefault(service)
+		reqHandler := http.HandlerFunc(h.GetByRegistration())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.JSONEq(t, `{"status":"Not Found","message":"No se encontró el vehículo con esa matrícula."}`, rr.Body.String())
+	})
 }
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"app/internal"
+	"net/http"
+	"strconv"
+
+	"github.com/bootcamp-go/web/response"
+	"github.com/go-chi/chi/v5"
+)
+
+// NewVehicleV2 is a function that returns a new instance of VehicleV2
+func NewVehicleV2(sv internal.VehicleService) *VehicleV2 {
+	return &VehicleV2{VehicleDefault: NewVehicleDefault(sv)}
+}
+
+// VehicleV2 is a struct with methods that represent handlers for the version 2 of the vehicle routes
+// - every collection route responds with ListJSON, even when no vehicle matches, and accepts sort, limit, offset and fields
+// - text criteria are compared case insensitively, as in Search
+// - the routes that do not return a collection are inherited from VehicleDefault
+type VehicleV2 struct {
+	*VehicleDefault
+}
+
+// GetAll is a method that returns a handler for the route GET /v2/vehicles
+func (h *VehicleV2) GetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.list(w, r)
+	}
+}
+
+// GetByColorAndYear is a method that returns a handler for the route GET /v2/vehicles/color/{color}/year/{year}
+func (h *VehicleV2) GetByColorAndYear() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get color and year from URL using chi
+		color := chi.URLParam(r, "color")
+		year, err := strconv.Atoi(chi.URLParam(r, "year"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Año mal formado.")
+			return
+		}
+
+		// process and response
+		h.list(w, r, eq("color", color), eq("year", year))
+	}
+}
+
+// GetByWeightRange is a method that returns a handler for the route GET /v2/vehicles/weight?min={weight_min}&max={weight_max}
+func (h *VehicleV2) GetByWeightRange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get min and max from query params
+		min, err := strconv.ParseFloat(r.URL.Query().Get("min"), 64)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Peso mínimo mal formado.")
+			return
+		}
+		max, err := strconv.ParseFloat(r.URL.Query().Get("max"), 64)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Peso máximo mal formado.")
+			return
+		}
+
+		// process and response
+		h.list(w, r, between("weight", min, max)...)
+	}
+}
+
+// GetByBrandAndYearRange is a method that returns a handler for the route GET /v2/vehicles/brand/{brand}/between/{start_year}/{end_year}
+func (h *VehicleV2) GetByBrandAndYearRange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		brand := chi.URLParam(r, "brand")
+		startYear, err := strconv.Atoi(chi.URLParam(r, "start_year"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Año de inicio mal formado.")
+			return
+		}
+		endYear, err := strconv.Atoi(chi.URLParam(r, "end_year"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Año de fin mal formado.")
+			return
+		}
+
+		// process and response
+		h.list(w, r, append(between("year", startYear, endYear), eq("brand", brand))...)
+	}
+}
+
+// GetByFuelType is a method that returns a handler for the route GET /v2/vehicles/fuel_type/{type}
+func (h *VehicleV2) GetByFuelType() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.list(w, r, eq("fuel_type", string(internal.NormalizeFuelType(chi.URLParam(r, "type")))))
+	}
+}
+
+// GetByTransmission is a method that returns a handler for the route GET /v2/vehicles/transmission/{type}
+func (h *VehicleV2) GetByTransmission() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.list(w, r, eq("transmission", string(internal.NormalizeTransmission(chi.URLParam(r, "type")))))
+	}
+}
+
+// GetByDimensions is a method that returns a handler for the route GET /v2/vehicles/dimensions?length={min_length}-{max_length}&width={min_width}-{max_width}
+func (h *VehicleV2) GetByDimensions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get length and width ranges from query params
+		minLength, maxLength, err := parseRange(r.URL.Query().Get("length"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Rango de largo mal formado.")
+			return
+		}
+		minWidth, maxWidth, err := parseRange(r.URL.Query().Get("width"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Rango de ancho mal formado.")
+			return
+		}
+
+		// process and response
+		h.list(w, r, append(between("length", minLength, maxLength), between("width", minWidth, maxWidth)...)...)
+	}
+}
+
+// Search is a method that returns a handler for the route GET /v2/vehicles/search?{field}[{operator}]={value}
+func (h *VehicleV2) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		// - get filters, ordering and pagination from query params
+		q, err := internal.ParseVehicleQuery(r.URL.Query())
+		if err != nil {
+			queryError(w, err)
+			return
+		}
+		fields, err := internal.ParseVehicleFields(r.URL.Query().Get(internal.QueryParamFields))
+		if err != nil {
+			queryError(w, err)
+			return
+		}
+
+		// process and response
+		h.findPage(w, r, "Vehículos encontrados exitosamente.", q, fields)
+	}
+}
@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"app/internal"
+	"app/internal/handler"
+	"app/internal/service"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestV2GetByBrandAndYearRange(t *testing.T) {
+	t.Run("should return status code 200 with a page of the vehicles in the list envelope", func(t *testing.T) {
+		// ARRANGE
+		// - page
+		page := internal.VehiclePage{
+			Vehicles: []internal.Vehicle{
+				*internal.NewVehicle(1001, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "Gasoline", "Automatic", 1300.0, 1.45, 4.62, 1.77),
+			},
+			Total: 2,
+		}
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindPage", internal.VehicleQuery{
+			Filters: []internal.VehicleFilter{
+				{Field: "year", Operator: internal.OpGte, Values: []any{2015}},
+				{Field: "year", Operator: internal.OpLte, Values: []any{2020}},
+				{Field: "brand", Operator: internal.OpEq, Values: []any{"Toyota"}},
+			},
+			Limit: 1,
+		}).Return(page, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/v2/vehicles/brand/Toyota/between/2015/2020?limit=1", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("brand", "Toyota")
+		routeContext.URLParams.Add("start_year", "2015")
+		routeContext.URLParams.Add("end_year", "2020")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleV2(service)
+		reqHandler := http.HandlerFunc(h.GetByBrandAndYearRange())
+
+		// - expected response
+		expectedResponse := `{"message":"Vehículos encontrados exitosamente.","data":[{"id":1001,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"Gasoline","transmission":"Automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}],"meta":{"total":2,"limit":1,"offset":0}}`
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, expectedResponse, rr.Body.String())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("should return status code 400 when the start year is malformed", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/v2/vehicles/brand/Toyota/between/abc/2020", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("brand", "Toyota")
+		routeContext.URLParams.Add("start_year", "abc")
+		routeContext.URLParams.Add("end_year", "2020")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleV2(service)
+		reqHandler := http.HandlerFunc(h.GetByBrandAndYearRange())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, `{"status":"Bad Request","message":"Año de inicio mal formado."}`, rr.Body.String())
+		service.AssertNotCalled(t, "FindPage", mock.Anything)
+	})
+}
+
+func TestV2GetByColorAndYear(t *testing.T) {
+	t.Run("should return status code 200 with an empty list when no vehicle matches", func(t *testing.T) {
+		// ARRANGE
+		// - service mock
+		service := new(service.VehicleDefaultMock)
+		// define mock behavior
+		service.On("FindPage", internal.VehicleQuery{
+			Filters: []internal.VehicleFilter{
+				{Field: "color", Operator: internal.OpEq, Values: []any{"Pink"}},
+				{Field: "year", Operator: internal.OpEq, Values: []any{1999}},
+			},
+		}).Return(internal.VehiclePage{}, nil)
+
+		// - request
+		req := httptest.NewRequest(http.MethodGet, "/v2/vehicles/color/Pink/year/1999", nil)
+		routeContext := chi.NewRouteContext()
+		routeContext.URLParams.Add("color", "Pink")
+		routeContext.URLParams.Add("year", "1999")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+		// - response recorder
+		rr := httptest.NewRecorder()
+		// - handler
+		h := handler.NewVehicleV2(service)
+		reqHandler := http.HandlerFunc(h.GetByColorAndYear())
+
+		// ACT
+		reqHandler.ServeHTTP(rr, req)
+
+		// ASSERT
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"message":"Vehículos encontrados exitosamente.","data":[],"meta":{"total":0,"limit":0,"offset":0}}`, rr.Body.String())
+		service.AssertExpectations(t)
+	})
+}
@@ -4,8 +4,13 @@ import (
 	"app/internal"
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"sort"
 )
 
+// defaultFileMode is the permissions of the file created by Save when it does not exist yet
+const defaultFileMode os.FileMode = 0o644
+
 // NewVehicleJSONFile is a function that returns a new instance of VehicleJSONFile
 func NewVehicleJSONFile(path string) *VehicleJSONFile {
 	return &VehicleJSONFile{
@@ -38,7 +43,42 @@ type VehicleJSON struct {
 }
 
 // Load is a method that loads the vehicles
+// - it fails with *internal.VehicleRegistrationConflictError if several vehicles share a registration, naming all of them
 func (l *VehicleJSONFile) Load() (v map[int]internal.Vehicle, err error) {
+	v, err = l.read()
+	if err != nil {
+		return
+	}
+
+	// registrations are unique, a file that breaks it can not be served as is
+	if c := internal.FindRegistrationConflicts(v); len(c) > 0 {
+		v = nil
+		err = &internal.VehicleRegistrationConflictError{Conflicts: c}
+		return
+	}
+	return
+}
+
+// LoadKeepFirst is a method that loads the vehicles resolving the registrations shared by several vehicles
+// - of each shared registration the vehicle with the lowest id is kept, the others are skipped
+// - skipped is the list of the shared registrations, the first id of each one is the vehicle kept
+func (l *VehicleJSONFile) LoadKeepFirst() (v map[int]internal.Vehicle, skipped []internal.VehicleRegistrationConflict, err error) {
+	v, err = l.read()
+	if err != nil {
+		return
+	}
+
+	skipped = internal.FindRegistrationConflicts(v)
+	for _, c := range skipped {
+		for _, id := range c.Ids[1:] {
+			delete(v, id)
+		}
+	}
+	return
+}
+
+// read is a method that reads the vehicles of the file as they are
+func (l *VehicleJSONFile) read() (v map[int]internal.Vehicle, err error) {
 	// open file
 	file, err := os.Open(l.path)
 	if err != nil {
@@ -53,7 +93,7 @@ func (l *VehicleJSONFile) Load() (v map[int]internal.Vehicle, err error) {
 		return
 	}
 
-	// serialize vehicles
+	// deserialize vehicles, with the fuel type and transmission normalized
 	v = make(map[int]internal.Vehicle)
 	for _, vh := range vehiclesJSON {
 		v[vh.Id] = internal.Vehicle{
@@ -66,8 +106,8 @@ func (l *VehicleJSONFile) Load() (v map[int]internal.Vehicle, err error) {
 				FabricationYear: vh.FabricationYear,
 				Capacity:        vh.Capacity,
 				MaxSpeed:        vh.MaxSpeed,
-				FuelType:        vh.FuelType,
-				Transmission:    vh.Transmission,
+				FuelType:        internal.NormalizeFuelType(vh.FuelType),
+				Transmission:    internal.NormalizeTransmission(vh.Transmission),
 				Weight:          vh.Weight,
 				Dimensions: internal.Dimensions{
 					Height: vh.Height,
@@ -80,3 +120,79 @@ func (l *VehicleJSONFile) Load() (v map[int]internal.Vehicle, err error) {
 
 	return
 }
+
+// Save is a method that saves the vehicles
+// - the file is replaced atomically by writing a temporary file in the same directory and renaming it
+// - the file keeps its permissions, a new one is created with defaultFileMode
+func (l *VehicleJSONFile) Save(v map[int]internal.Vehicle) (err error) {
+	// serialize vehicles, sorted by id
+	vehiclesJSON := make([]VehicleJSON, 0, len(v))
+	for _, vh := range v {
+		vehiclesJSON = append(vehiclesJSON, VehicleJSON{
+			Id:              vh.Id,
+			Brand:           vh.Brand,
+			Model:           vh.Model,
+			Registration:    vh.Registration,
+			Color:           vh.Color,
+			FabricationYear: vh.FabricationYear,
+			Capacity:        vh.Capacity,
+			MaxSpeed:        vh.MaxSpeed,
+			FuelType:        string(vh.FuelType),
+			Transmission:    string(vh.Transmission),
+			Weight:          vh.Weight,
+			Height:          vh.Height,
+			Length:          vh.Length,
+			Width:           vh.Width,
+		})
+	}
+	sort.Slice(vehiclesJSON, func(i, j int) bool {
+		return vehiclesJSON[i].Id < vehiclesJSON[j].Id
+	})
+
+	// write temporary file
+	file, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(vehiclesJSON)
+	if err != nil {
+		file.Close()
+		return
+	}
+	// - os.CreateTemp creates the file only readable by its owner
+	mode := defaultFileMode
+	if info, e := os.Stat(l.path); e == nil {
+		mode = info.Mode().Perm()
+	}
+	err = file.Chmod(mode)
+	if err != nil {
+		file.Close()
+		return
+	}
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return
+	}
+	err = file.Close()
+	if err != nil {
+		return
+	}
+
+	// replace file, syncing the directory so that the rename survives a crash
+	err = os.Rename(file.Name(), l.path)
+	if err != nil {
+		return
+	}
+	dir, err := os.Open(filepath.Dir(l.path))
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+	err = dir.Sync()
+	return
+}
@@ -0,0 +1,62 @@
+package loader_test
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// seedFile is the seed file shipped with the repository, it has registrations shared by several vehicles
+const seedFile = "../../docs/db/vehicles_100.json"
+
+// seedConflicts is the list of the registrations shared by several vehicles of the seed file
+var seedConflicts = []internal.VehicleRegistrationConflict{
+	{Registration: "0", Ids: []int{1, 7, 67}},
+	{Registration: "3", Ids: []int{10, 39, 61}},
+	{Registration: "9", Ids: []int{20, 74, 79, 94, 100}},
+	{Registration: "2", Ids: []int{27, 69, 92}},
+	{Registration: "6", Ids: []int{47, 98}},
+	{Registration: "4", Ids: []int{50, 57, 68}},
+}
+
+func TestVehicleJSONFile_Load(t *testing.T) {
+	t.Run("should fail naming every vehicle that shares a registration", func(t *testing.T) {
+		// ARRANGE
+		ld := loader.NewVehicleJSONFile(seedFile)
+
+		// ACT
+		v, err := ld.Load()
+
+		// ASSERT
+		var conflictErr *internal.VehicleRegistrationConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.ErrorIs(t, err, internal.ErrVehicleRegistrationConflict)
+		require.Equal(t, seedConflicts, conflictErr.Conflicts)
+		require.ErrorContains(t, err, `"9" shared by ids [20 74 79 94 100]`)
+		require.Nil(t, v)
+	})
+}
+
+func TestVehicleJSONFile_LoadKeepFirst(t *testing.T) {
+	t.Run("should keep the lowest id of each shared registration and skip the others", func(t *testing.T) {
+		// ARRANGE
+		ld := loader.NewVehicleJSONFile(seedFile)
+
+		// ACT
+		v, skipped, err := ld.LoadKeepFirst()
+
+		// ASSERT
+		require.NoError(t, err)
+		require.Equal(t, seedConflicts, skipped)
+		require.Len(t, v, 87)
+		for _, c := range skipped {
+			require.Contains(t, v, c.Ids[0])
+			for _, id := range c.Ids[1:] {
+				require.NotContains(t, v, id)
+			}
+		}
+		require.Empty(t, internal.FindRegistrationConflicts(v))
+	})
+}
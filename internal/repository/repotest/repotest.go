@@ -0,0 +1,916 @@
+// Package repotest provides a conformance suite for implementations of internal.VehicleRepository,
+// so that every backend is verified against identical expectations.
+package repotest
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Factory is a function that returns a repository holding the given vehicles
+// - db may be nil, in which case the repository must be empty
+// - the factory owns db, the repository may keep it
+type Factory func(t *testing.T, db map[int]internal.Vehicle) internal.VehicleRepository
+
+// Fixtures is a function that returns the vehicles the suite seeds most repositories with
+func Fixtures() map[int]internal.Vehicle {
+	return map[int]internal.Vehicle{
+		1: *internal.NewVehicle(1, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77),
+		2: *internal.NewVehicle(2, "Toyota", "Yaris", "DEF-5678", "Red", 2015, 4, 160.0, "diesel", "manual", 1000.0, 1.5, 3.9, 1.7),
+		3: *internal.NewVehicle(3, "Ford", "Fiesta", "GHI-9012", "Blue", 2020, 5, 170.0, "gasoline", "manual", 1100.0, 1.4, 4.0, 1.7),
+	}
+}
+
+// Run is a function that runs the behavior expected from every internal.VehicleRepository
+// - each case builds a fresh repository through the factory
+func Run(t *testing.T, factory Factory) {
+	t.Run("FindAll should return every vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindAll(context.Background())
+
+		require.NoError(t, err)
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("FindAll should return an empty map when there are no vehicles", func(t *testing.T) {
+		rp := factory(t, nil)
+
+		v, err := rp.FindAll(context.Background())
+
+		require.NoError(t, err)
+		require.NotNil(t, v)
+		require.Empty(t, v)
+	})
+
+	t.Run("FindAll should return a copy that does not alter the repository", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, _ := rp.FindAll(context.Background())
+		delete(v, 1)
+		v[2] = internal.Vehicle{Id: 2}
+
+		v, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Create should add the vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		vehicle := internal.NewVehicle(4, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)
+
+		err := rp.Create(context.Background(), vehicle)
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, *vehicle, v[4])
+	})
+
+	t.Run("Create should store a copy of the vehicle", func(t *testing.T) {
+		rp := factory(t, nil)
+		vehicle := internal.NewVehicle(4, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)
+
+		err := rp.Create(context.Background(), vehicle)
+		vehicle.Brand = "Changed"
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, "Ford", v[4].Brand)
+	})
+
+	t.Run("Create should fail when the id already exists", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Create(context.Background(), internal.NewVehicle(1, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6))
+
+		require.ErrorIs(t, err, internal.ErrVehicleAlreadyExists)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Create should fail when mandatory fields are missing", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Create(context.Background(), internal.NewVehicle(4, "", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6))
+
+		require.ErrorIs(t, err, internal.ErrVehicleMandatoryFields)
+	})
+
+	t.Run("Create should allocate the next id when it is omitted", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		vehicle := internal.NewVehicle(0, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)
+
+		err := rp.Create(context.Background(), vehicle)
+
+		require.NoError(t, err)
+		require.Equal(t, 4, vehicle.Id)
+		v, err := rp.FindById(context.Background(), 4)
+		require.NoError(t, err)
+		require.Equal(t, *vehicle, v)
+	})
+
+	t.Run("Create should allocate ids after explicit ones and never reuse them", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		require.NoError(t, rp.Create(context.Background(), internal.NewVehicle(10, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)))
+		require.NoError(t, rp.Delete(context.Background(), 10))
+		vehicle := internal.NewVehicle(0, "Ford", "Ka", "MNO-7890", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)
+		err := rp.Create(context.Background(), vehicle)
+
+		require.NoError(t, err)
+		require.Equal(t, 11, vehicle.Id)
+	})
+
+	t.Run("Create should not consume an id when the vehicle is invalid", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		invalid := internal.NewVehicle(0, "", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)
+		vehicle := internal.NewVehicle(0, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)
+
+		require.Error(t, rp.Create(context.Background(), invalid))
+		err := rp.Create(context.Background(), vehicle)
+
+		require.NoError(t, err)
+		require.Zero(t, invalid.Id)
+		require.Equal(t, 4, vehicle.Id)
+	})
+
+	t.Run("BatchCreate should create every vehicle when the batch is valid", func(t *testing.T) {
+		rp := factory(t, nil)
+		batch := []*internal.Vehicle{
+			internal.NewVehicle(1, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77),
+			internal.NewVehicle(2, "Ford", "Fiesta", "DEF-5678", "Red", 2019, 5, 180.0, "gasoline", "manual", 1300.0, 1.45, 4.62, 1.77),
+		}
+
+		err := rp.BatchCreate(context.Background(), batch)
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		require.Len(t, v, 2)
+	})
+
+	t.Run("BatchCreate should accept an empty batch", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.BatchCreate(context.Background(), []*internal.Vehicle{})
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("BatchCreate should create no vehicle and report every failing index when the batch is invalid", func(t *testing.T) {
+		rp := factory(t, map[int]internal.Vehicle{
+			1: *internal.NewVehicle(1, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77),
+		})
+		batch := []*internal.Vehicle{
+			internal.NewVehicle(2, "Ford", "Fiesta", "DEF-5678", "Red", 2019, 5, 180.0, "gasoline", "manual", 1300.0, 1.45, 4.62, 1.77),
+			internal.NewVehicle(1, "Ford", "Focus", "GHI-9012", "Black", 2018, 5, 190.0, "diesel", "manual", 1200.0, 1.4, 4.3, 1.8),
+			internal.NewVehicle(2, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6),
+			internal.NewVehicle(0, "", "Ka", "MNO-7890", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6),
+		}
+
+		err := rp.BatchCreate(context.Background(), batch)
+
+		var batchErr *internal.VehicleBatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Len(t, batchErr.Items, 3)
+		require.Equal(t, internal.VehicleBatchItemError{Index: 1, Id: 1, Err: internal.ErrVehicleAlreadyExists}, batchErr.Items[0])
+		require.Equal(t, internal.VehicleBatchItemError{Index: 2, Id: 2, Err: internal.ErrVehicleDuplicatedInBatch}, batchErr.Items[1])
+		require.Equal(t, 3, batchErr.Items[2].Index)
+		require.Zero(t, batchErr.Items[2].Id)
+		require.ErrorIs(t, batchErr.Items[2].Err, internal.ErrVehicleMandatoryFields)
+		require.Zero(t, batch[3].Id)
+		v, _ := rp.FindAll(context.Background())
+		require.Len(t, v, 1)
+	})
+
+	t.Run("BatchCreate should allocate the omitted ids after the highest id of the repository and of the batch", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		batch := []*internal.Vehicle{
+			internal.NewVehicle(0, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6),
+			internal.NewVehicle(7, "Ford", "Focus", "MNO-7890", "Black", 2018, 5, 190.0, "diesel", "manual", 1200.0, 1.4, 4.3, 1.8),
+			internal.NewVehicle(0, "Ford", "Fiesta", "PQR-1234", "Red", 2019, 5, 180.0, "gasoline", "manual", 1300.0, 1.45, 4.62, 1.77),
+		}
+
+		err := rp.BatchCreate(context.Background(), batch)
+
+		require.NoError(t, err)
+		require.Equal(t, []int{8, 7, 9}, []int{batch[0].Id, batch[1].Id, batch[2].Id})
+		v, _ := rp.FindAll(context.Background())
+		require.Len(t, v, 6)
+		require.Equal(t, "Fiesta", v[9].Model)
+	})
+
+	t.Run("FindByColorAndYear should return the matching vehicles", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindByColorAndYear(context.Background(), "Blue", 2020)
+
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		require.Contains(t, v, 1)
+		require.Contains(t, v, 3)
+	})
+
+	t.Run("FindByColorAndYear should fail when no vehicle matches", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindByColorAndYear(context.Background(), "Blue", 1999)
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("Delete should remove the vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Delete(context.Background(), 1)
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		require.NotContains(t, v, 1)
+		require.Len(t, v, 2)
+	})
+
+	t.Run("Delete should fail when the vehicle does not exist", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Delete(context.Background(), 99)
+
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+	})
+
+	t.Run("UpdateFuelType should update the fuel type", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.UpdateFuelType(context.Background(), 1, "diesel")
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		expected := Fixtures()[1]
+		expected.FuelType = "diesel"
+		require.Equal(t, expected, v[1])
+	})
+
+	t.Run("UpdateFuelType should fail when the vehicle does not exist", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.UpdateFuelType(context.Background(), 99, "diesel")
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+
+		// not found takes precedence over an invalid fuel type
+		err = rp.UpdateFuelType(context.Background(), 99, "petrol")
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+	})
+
+	t.Run("UpdateFuelType should succeed when the fuel type does not change", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.UpdateFuelType(context.Background(), 1, Fixtures()[1].FuelType)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("UpdateFuelType should fail and keep the vehicle when the fuel type is not canonical", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		for _, fuelType := range []internal.FuelType{"petrol", "Diesel", ""} {
+			err := rp.UpdateFuelType(context.Background(), 1, fuelType)
+
+			require.ErrorIs(t, err, internal.ErrVehicleInvalidFuelType)
+		}
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("FindByWeightRange should return the vehicles within the inclusive range", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindByWeightRange(context.Background(), 1000, 1100)
+
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		require.Contains(t, v, 2)
+		require.Contains(t, v, 3)
+	})
+
+	t.Run("FindByWeightRange should fail when no vehicle matches", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindByWeightRange(context.Background(), 5000, 6000)
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("FindByBrandAndYearRange should return the vehicles within the inclusive range", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindByBrandAndYearRange(context.Background(), "Toyota", 2015, 2019)
+
+		require.NoError(t, err)
+		require.Len(t, v, 1)
+		require.Contains(t, v, 2)
+	})
+
+	t.Run("FindByBrandAndYearRange should fail when no vehicle matches", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindByBrandAndYearRange(context.Background(), "Ford", 2000, 2010)
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("FindAverageMaxSpeedByBrand should return the average max speed", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		avg, err := rp.FindAverageMaxSpeedByBrand(context.Background(), "Toyota")
+
+		require.NoError(t, err)
+		require.InDelta(t, 170.0, avg, 1e-9)
+	})
+
+	t.Run("FindAverageMaxSpeedByBrand should fail when the brand has no vehicles", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindAverageMaxSpeedByBrand(context.Background(), "Ferrari")
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("UpdateMaxSpeed should update the max speed", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.UpdateMaxSpeed(context.Background(), 1, 200.0)
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		expected := Fixtures()[1]
+		expected.MaxSpeed = 200.0
+		require.Equal(t, expected, v[1])
+	})
+
+	t.Run("UpdateMaxSpeed should accept the limit", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.UpdateMaxSpeed(context.Background(), 1, repository.MaxSpeedLimit)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("UpdateMaxSpeed should succeed when the max speed does not change", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.UpdateMaxSpeed(context.Background(), 1, Fixtures()[1].MaxSpeed)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("UpdateMaxSpeed should fail when the vehicle does not exist", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.UpdateMaxSpeed(context.Background(), 99, 200.0)
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+
+		// not found takes precedence over an invalid max speed
+		err = rp.UpdateMaxSpeed(context.Background(), 99, -1)
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+	})
+
+	t.Run("UpdateMaxSpeed should fail when the max speed is out of range", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		for _, maxSpeed := range []float64{0, -1, repository.MaxSpeedLimit + 1} {
+			err := rp.UpdateMaxSpeed(context.Background(), 1, maxSpeed)
+
+			require.ErrorIs(t, err, internal.ErrVehicleInvalidMaxSpeed)
+		}
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, 180.0, v[1].MaxSpeed)
+	})
+
+	t.Run("FindByFuelType should match case insensitively", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindByFuelType(context.Background(), "GASOLINE")
+
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		require.Contains(t, v, 1)
+		require.Contains(t, v, 3)
+	})
+
+	t.Run("FindByFuelType should fail when no vehicle matches", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindByFuelType(context.Background(), "electric")
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("FindByTransmission should match case insensitively", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindByTransmission(context.Background(), "manual")
+
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		require.Contains(t, v, 2)
+		require.Contains(t, v, 3)
+	})
+
+	t.Run("FindByTransmission should fail when no vehicle matches", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindByTransmission(context.Background(), "cvt")
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("CountByFuelType should return the number of vehicles of each fuel type", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		count, err := rp.CountByFuelType(context.Background())
+
+		require.NoError(t, err)
+		require.Equal(t, map[internal.FuelType]int{"gasoline": 2, "diesel": 1}, count)
+	})
+
+	t.Run("CountByFuelType should follow the writes", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		require.NoError(t, rp.UpdateFuelType(context.Background(), 1, "electric"))
+		require.NoError(t, rp.Delete(context.Background(), 2))
+
+		count, err := rp.CountByFuelType(context.Background())
+
+		require.NoError(t, err)
+		require.Equal(t, map[internal.FuelType]int{"gasoline": 1, "electric": 1}, count)
+	})
+
+	t.Run("CountByFuelType should return nothing for an empty repository", func(t *testing.T) {
+		rp := factory(t, map[int]internal.Vehicle{})
+
+		count, err := rp.CountByFuelType(context.Background())
+
+		require.NoError(t, err)
+		require.Empty(t, count)
+	})
+
+	t.Run("FindAverageCapacityByBrand should return the average capacity", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		avg, err := rp.FindAverageCapacityByBrand(context.Background(), "Toyota")
+
+		require.NoError(t, err)
+		require.InDelta(t, 4.5, avg, 1e-9)
+	})
+
+	t.Run("FindAverageCapacityByBrand should fail when the brand has no vehicles", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindAverageCapacityByBrand(context.Background(), "Ferrari")
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("FindByDimensions should return the vehicles within both inclusive ranges", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindByDimensions(context.Background(), 3.9, 4.0, 1.7, 1.7)
+
+		require.NoError(t, err)
+		require.Len(t, v, 2)
+		require.Contains(t, v, 2)
+		require.Contains(t, v, 3)
+	})
+
+	t.Run("FindByDimensions should fail when no vehicle matches", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindByDimensions(context.Background(), 10, 20, 1, 2)
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("Find should return every vehicle when the query has no filters", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.Find(context.Background(), internal.VehicleQuery{})
+
+		require.NoError(t, err)
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Find should combine filters with AND", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{Filters: []internal.VehicleFilter{
+			{Field: "brand", Operator: internal.OpEq, Values: []any{"toyota"}},
+			{Field: "year", Operator: internal.OpGte, Values: []any{2016}},
+			{Field: "max_speed", Operator: internal.OpLt, Values: []any{200.0}},
+		}}
+
+		v, err := rp.Find(context.Background(), q)
+
+		require.NoError(t, err)
+		require.Len(t, v, 1)
+		require.Contains(t, v, 1)
+	})
+
+	t.Run("Find should match any value of an in filter", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{Filters: []internal.VehicleFilter{
+			{Field: "color", Operator: internal.OpIn, Values: []any{"red", "Green"}},
+			{Field: "passengers", Operator: internal.OpIn, Values: []any{4, 5}},
+		}}
+
+		v, err := rp.Find(context.Background(), q)
+
+		require.NoError(t, err)
+		require.Len(t, v, 1)
+		require.Contains(t, v, 2)
+	})
+
+	t.Run("Find should match substrings case insensitively", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{Filters: []internal.VehicleFilter{
+			{Field: "registration", Operator: internal.OpContains, Values: []any{"def-"}},
+		}}
+
+		v, err := rp.Find(context.Background(), q)
+
+		require.NoError(t, err)
+		require.Len(t, v, 1)
+		require.Contains(t, v, 2)
+	})
+
+	t.Run("Find should treat wildcards literally", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{Filters: []internal.VehicleFilter{
+			{Field: "model", Operator: internal.OpContains, Values: []any{"%"}},
+		}}
+
+		_, err := rp.Find(context.Background(), q)
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("Find should fail when no vehicle matches", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{Filters: []internal.VehicleFilter{
+			{Field: "weight", Operator: internal.OpGt, Values: []any{5000.0}},
+		}}
+
+		_, err := rp.Find(context.Background(), q)
+
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("Find and FindPage should fail when the query is invalid", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		cases := map[string]internal.VehicleQuery{
+			"unknown field":        {Filters: []internal.VehicleFilter{{Field: "id = 1 OR 1", Operator: internal.OpEq, Values: []any{1}}}},
+			"unsupported operator": {Filters: []internal.VehicleFilter{{Field: "year", Operator: internal.OpContains, Values: []any{2020}}}},
+			"unknown operator":     {Filters: []internal.VehicleFilter{{Field: "year", Operator: "ne", Values: []any{2020}}}},
+			"no values":            {Filters: []internal.VehicleFilter{{Field: "year", Operator: internal.OpEq}}},
+			"empty in":             {Filters: []internal.VehicleFilter{{Field: "year", Operator: internal.OpIn, Values: []any{}}}},
+			"several values":       {Filters: []internal.VehicleFilter{{Field: "year", Operator: internal.OpGt, Values: []any{2018, 2020}}}},
+			"value of other kind":  {Filters: []internal.VehicleFilter{{Field: "year", Operator: internal.OpEq, Values: []any{"2020"}}}},
+			"unknown sort field":   {Sort: []internal.VehicleSort{{Field: "id; DROP TABLE vehicles"}}},
+			"negative offset":      {Offset: -1},
+		}
+		for name, q := range cases {
+			var queryErr *internal.VehicleQueryError
+
+			_, err := rp.Find(context.Background(), q)
+			require.ErrorAs(t, err, &queryErr, name)
+
+			_, err = rp.FindPage(context.Background(), q)
+			require.ErrorAs(t, err, &queryErr, name)
+		}
+		// - the vehicles are untouched
+		v, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("FindPage should return every vehicle by id when the query has no sort nor pagination", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		p, err := rp.FindPage(context.Background(), internal.VehicleQuery{})
+
+		require.NoError(t, err)
+		require.Equal(t, 3, p.Total)
+		require.Equal(t, []int{1, 2, 3}, ids(p.Vehicles))
+		require.Equal(t, Fixtures()[2], p.Vehicles[1])
+	})
+
+	t.Run("FindPage should sort by every key and break ties by id", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{Sort: []internal.VehicleSort{{Field: "year", Desc: true}}}
+
+		p, err := rp.FindPage(context.Background(), q)
+
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 3, 2}, ids(p.Vehicles))
+	})
+
+	t.Run("FindPage should sort text case insensitively", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{Sort: []internal.VehicleSort{{Field: "transmission", Desc: true}}}
+
+		p, err := rp.FindPage(context.Background(), q)
+
+		require.NoError(t, err)
+		require.Equal(t, []int{2, 3, 1}, ids(p.Vehicles))
+	})
+
+	t.Run("FindPage should paginate the matching vehicles and count all of them", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		q := internal.VehicleQuery{
+			Filters: []internal.VehicleFilter{{Field: "color", Operator: internal.OpEq, Values: []any{"blue"}}},
+			Sort:    []internal.VehicleSort{{Field: "max_speed", Desc: true}},
+			Limit:   1,
+			Offset:  1,
+		}
+
+		p, err := rp.FindPage(context.Background(), q)
+
+		require.NoError(t, err)
+		require.Equal(t, 2, p.Total)
+		require.Equal(t, []int{3}, ids(p.Vehicles))
+	})
+
+	t.Run("FindPage should return an empty page past the last vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		p, err := rp.FindPage(context.Background(), internal.VehicleQuery{Limit: 10, Offset: 3})
+
+		require.NoError(t, err)
+		require.Equal(t, 3, p.Total)
+		require.Empty(t, p.Vehicles)
+	})
+
+	t.Run("Update should replace every attribute of the vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		vehicle := internal.NewVehicle(2, "Toyota", "Yaris Cross", "DEF-5678", "Green", 2022, 5, 175.0, "hybrid", "automatic", 1250.0, 1.6, 4.2, 1.8)
+
+		err := rp.Update(context.Background(), vehicle)
+
+		require.NoError(t, err)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, *vehicle, v[2])
+		p, _ := rp.FindPage(context.Background(), internal.VehicleQuery{Filters: []internal.VehicleFilter{{Field: "color", Operator: internal.OpEq, Values: []any{"Green"}}}})
+		require.Equal(t, []int{2}, ids(p.Vehicles))
+		_, err = rp.FindByColorAndYear(context.Background(), "Red", 2015)
+		require.ErrorIs(t, err, internal.ErrVehiclesNotFound)
+	})
+
+	t.Run("Update should fail when the vehicle does not exist", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Update(context.Background(), internal.NewVehicle(99, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6))
+
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Update should fail and keep the vehicle when the result is invalid", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Update(context.Background(), internal.NewVehicle(2, "Toyota", "Yaris", "DEF-5678", "Red", 2015, 4, 900.0, "diesel", "manual", 1000.0, 1.5, 3.9, 1.7))
+
+		require.ErrorIs(t, err, internal.ErrVehicleInvalidMaxSpeed)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Patch should store and return the changed vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.Patch(context.Background(), 3, func(v *internal.Vehicle) error {
+			v.Color = "Black"
+			v.Weight = 1150.0
+			return nil
+		})
+
+		require.NoError(t, err)
+		expected := Fixtures()[3]
+		expected.Color = "Black"
+		expected.Weight = 1150.0
+		require.Equal(t, expected, v)
+		all, _ := rp.FindAll(context.Background())
+		require.Equal(t, expected, all[3])
+		found, err := rp.FindByWeightRange(context.Background(), 1150, 1150)
+		require.NoError(t, err)
+		require.Contains(t, found, 3)
+	})
+
+	t.Run("Patch should not change the id of the vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.Patch(context.Background(), 3, func(v *internal.Vehicle) error {
+			v.Id = 1
+			v.Model = "Puma"
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, 3, v.Id)
+		all, _ := rp.FindAll(context.Background())
+		require.Equal(t, "Puma", all[3].Model)
+		require.Equal(t, Fixtures()[1], all[1])
+	})
+
+	t.Run("Patch should fail when the vehicle does not exist", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.Patch(context.Background(), 99, func(v *internal.Vehicle) error { return nil })
+
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+	})
+
+	t.Run("Patch should fail and keep the vehicle when the change or its result is invalid", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		errPatch := errors.New("patch")
+
+		_, err := rp.Patch(context.Background(), 1, func(v *internal.Vehicle) error {
+			v.Color = "Black"
+			return errPatch
+		})
+		require.ErrorIs(t, err, errPatch)
+		_, err = rp.Patch(context.Background(), 1, func(v *internal.Vehicle) error {
+			v.Color = "Black"
+			v.MaxSpeed = -1
+			return nil
+		})
+		require.ErrorIs(t, err, internal.ErrVehicleInvalidMaxSpeed)
+
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("FindById should return the vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindById(context.Background(), 2)
+
+		require.NoError(t, err)
+		require.Equal(t, Fixtures()[2], v)
+	})
+
+	t.Run("FindById should fail when the vehicle does not exist", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindById(context.Background(), 99)
+
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+	})
+
+	t.Run("FindByRegistration should return the vehicle case insensitively", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		v, err := rp.FindByRegistration(context.Background(), "def-5678")
+
+		require.NoError(t, err)
+		require.Equal(t, Fixtures()[2], v)
+	})
+
+	t.Run("FindByRegistration should fail when no vehicle has the registration", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.FindByRegistration(context.Background(), "ZZZ-0000")
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+		_, err = rp.FindByRegistration(context.Background(), "")
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+	})
+
+	t.Run("Create should fail when the registration already exists", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Create(context.Background(), internal.NewVehicle(4, "Ford", "Ka", "abc-1234", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6))
+
+		require.ErrorIs(t, err, internal.ErrVehicleRegistrationAlreadyExists)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Create should report every rule broken by the vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		err := rp.Create(context.Background(), internal.NewVehicle(4, " ", "Ka", "", "White", 3000, 0, 900.0, "petrol", "", -1, 1.4, -1, 1.6))
+
+		var validationErr *internal.VehicleValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.ErrorIs(t, err, internal.ErrVehicleInvalidMaxSpeed)
+		require.Equal(t, []internal.VehicleFieldError{
+			{Field: "brand", Rule: internal.RuleRequired},
+			{Field: "registration", Rule: internal.RuleRequired},
+			{Field: "year", Rule: internal.RuleMax, Limit: float64(time.Now().Year())},
+			{Field: "passengers", Rule: internal.RuleMin, Limit: 1},
+			{Field: "max_speed", Rule: internal.RuleMax, Limit: repository.MaxSpeedLimit},
+			{Field: "fuel_type", Rule: internal.RuleOneOf, Allowed: []string{"gasoline", "gas", "diesel", "biodiesel", "electric", "hybrid"}},
+			{Field: "transmission", Rule: internal.RuleRequired},
+			{Field: "weight", Rule: internal.RulePositive},
+			{Field: "length", Rule: internal.RuleMin, Limit: 0},
+		}, validationErr.Fields)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Delete should free the registration of the vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		require.NoError(t, rp.Delete(context.Background(), 1))
+		err := rp.Create(context.Background(), internal.NewVehicle(4, "Ford", "Ka", "ABC-1234", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6))
+
+		require.NoError(t, err)
+		v, err := rp.FindByRegistration(context.Background(), "ABC-1234")
+		require.NoError(t, err)
+		require.Equal(t, 4, v.Id)
+	})
+
+	t.Run("BatchCreate should report registrations that already exist or repeat within the batch", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		batch := []*internal.Vehicle{
+			internal.NewVehicle(4, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6),
+			internal.NewVehicle(5, "Ford", "Ka", "GHI-9012", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6),
+			internal.NewVehicle(6, "Ford", "Ka", "jkl-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6),
+		}
+
+		err := rp.BatchCreate(context.Background(), batch)
+
+		var batchErr *internal.VehicleBatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []internal.VehicleBatchItemError{
+			{Index: 1, Id: 5, Err: internal.ErrVehicleRegistrationAlreadyExists},
+			{Index: 2, Id: 6, Err: internal.ErrVehicleRegistrationDuplicatedInBatch},
+		}, batchErr.Items)
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Update and Patch should fail when the registration belongs to another vehicle", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		vehicle := Fixtures()[1]
+		vehicle.Registration = "DEF-5678"
+
+		err := rp.Update(context.Background(), &vehicle)
+		require.ErrorIs(t, err, internal.ErrVehicleRegistrationAlreadyExists)
+		_, err = rp.Patch(context.Background(), 3, func(v *internal.Vehicle) error {
+			v.Registration = "def-5678"
+			return nil
+		})
+		require.ErrorIs(t, err, internal.ErrVehicleRegistrationAlreadyExists)
+
+		v, _ := rp.FindAll(context.Background())
+		require.Equal(t, Fixtures(), v)
+	})
+
+	t.Run("Patch should move the registration index to the new registration", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+
+		_, err := rp.Patch(context.Background(), 3, func(v *internal.Vehicle) error {
+			v.Registration = "NEW-0003"
+			return nil
+		})
+
+		require.NoError(t, err)
+		_, err = rp.FindByRegistration(context.Background(), "GHI-9012")
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+		v, err := rp.FindByRegistration(context.Background(), "new-0003")
+		require.NoError(t, err)
+		require.Equal(t, 3, v.Id)
+	})
+
+	t.Run("should fail with the error of a cancelled context and leave the repository unchanged", func(t *testing.T) {
+		rp := factory(t, Fixtures())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := rp.FindById(ctx, 1)
+		require.ErrorIs(t, err, context.Canceled)
+		_, err = rp.FindPage(ctx, internal.VehicleQuery{})
+		require.ErrorIs(t, err, context.Canceled)
+		err = rp.Create(ctx, internal.NewVehicle(0, "Ford", "Ka", "JKL-3456", "Red", 2019, 4, 150.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6))
+		require.ErrorIs(t, err, context.Canceled)
+		err = rp.Delete(ctx, 1)
+		require.ErrorIs(t, err, context.Canceled)
+
+		v, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, Fixtures(), v)
+	})
+}
+
+// ids is a function that returns the ids of a list of vehicles, in order
+func ids(v []internal.Vehicle) (ids []int) {
+	for _, vh := range v {
+		ids = append(ids, vh.Id)
+	}
+	return
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"app/internal"
+)
+
+// NewVehicleFile is a function that returns a new instance of VehicleFile
+func NewVehicleFile(db map[int]internal.Vehicle, sv internal.VehicleSaver) *VehicleFile {
+	r := &VehicleFile{
+		VehicleMap: NewVehicleMap(db),
+		sv:         sv,
+	}
+	r.VehicleMap.onCommit = r.save
+	return r
+}
+
+// VehicleFile is a struct that represents a vehicle repository that persists every change
+// - queries and mutations are served by the embedded VehicleMap, which saves every change through save before applying it
+// - the write lock of VehicleMap serializes the saves in the same order as the changes
+type VehicleFile struct {
+	*VehicleMap
+	// sv is the saver used to persist the vehicles
+	sv internal.VehicleSaver
+}
+
+// save is a method that saves the vehicles with a change applied, called by the embedded VehicleMap under its write lock
+// - the change is applied to a copy of the vehicles only, the repository and its indexes are updated once the save succeeds
+func (r *VehicleFile) save(put []internal.Vehicle, remove []int) (err error) {
+	db := make(map[int]internal.Vehicle, len(r.VehicleMap.db)+len(put))
+	for key, value := range r.VehicleMap.db {
+		db[key] = value
+	}
+	for _, v := range put {
+		db[v.Id] = v
+	}
+	for _, id := range remove {
+		delete(db, id)
+	}
+
+	err = r.sv.Save(db)
+	return
+}
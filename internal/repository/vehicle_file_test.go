@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"app/internal/repository"
+	"app/internal/repository/repotest"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// saverFunc is a function that implements internal.VehicleSaver
+type saverFunc func(v map[int]internal.Vehicle) error
+
+// Save is a method that calls the function
+func (f saverFunc) Save(v map[int]internal.Vehicle) error {
+	return f(v)
+}
+
+func TestVehicleFile_Persistence(t *testing.T) {
+	t.Run("should write every mutation back to the file", func(t *testing.T) {
+		// ARRANGE
+		// - file with one vehicle
+		path := filepath.Join(t.TempDir(), "vehicles.json")
+		err := os.WriteFile(path, []byte(`[{"id":1,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"gasoline","transmission":"automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}]`), 0o644)
+		require.NoError(t, err)
+		require.NoError(t, os.Chmod(path, 0o640))
+		ld := loader.NewVehicleJSONFile(path)
+		db, err := ld.Load()
+		require.NoError(t, err)
+		rp := repository.NewVehicleFile(db, ld)
+
+		// ACT
+		err = rp.Create(context.Background(), internal.NewVehicle(2, "Ford", "Fiesta", "DEF-5678", "Red", 2019, 5, 170.0, "diesel", "manual", 1100.0, 1.4, 4.0, 1.7))
+		require.NoError(t, err)
+		err = rp.BatchCreate(context.Background(), []*internal.Vehicle{
+			internal.NewVehicle(3, "Ford", "Focus", "GHI-9012", "Black", 2018, 5, 190.0, "diesel", "manual", 1200.0, 1.4, 4.3, 1.8),
+		})
+		require.NoError(t, err)
+		err = rp.UpdateFuelType(context.Background(), 1, "diesel")
+		require.NoError(t, err)
+		err = rp.Delete(context.Background(), 2)
+		require.NoError(t, err)
+
+		// ASSERT
+		// - a fresh load sees the same vehicles as the repository
+		expected, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		v, err := loader.NewVehicleJSONFile(path).Load()
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+		require.Equal(t, internal.FuelTypeDiesel, v[1].FuelType)
+		require.NotContains(t, v, 2)
+		// - no temporary files are left behind and the file keeps its permissions
+		entries, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	})
+
+	t.Run("should leave the repository unchanged when the save fails", func(t *testing.T) {
+		// ARRANGE
+		// - saver that fails until it is fixed
+		errSave := errors.New("disk full")
+		var saveErr error = errSave
+		sv := saverFunc(func(v map[int]internal.Vehicle) error { return saveErr })
+		rp := repository.NewVehicleFile(repotest.Fixtures(), sv)
+		vehicle := internal.NewVehicle(0, "Ford", "Ka", "JKL-3456", "Red", 2019, 4, 150.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)
+
+		// ACT
+		err := rp.Create(context.Background(), vehicle)
+
+		// ASSERT
+		require.ErrorIs(t, err, errSave)
+		v, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, repotest.Fixtures(), v)
+		_, err = rp.FindByRegistration(context.Background(), "JKL-3456")
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+		// - a retry once the saver works again succeeds
+		saveErr = nil
+		vehicle.Id = 0
+		require.NoError(t, rp.Create(context.Background(), vehicle))
+		require.Equal(t, 4, vehicle.Id)
+	})
+
+	t.Run("should not write the file when the mutation fails", func(t *testing.T) {
+		// ARRANGE
+		path := filepath.Join(t.TempDir(), "vehicles.json")
+		rp := repository.NewVehicleFile(nil, loader.NewVehicleJSONFile(path))
+
+		// ACT
+		err := rp.Delete(context.Background(), 1)
+
+		// ASSERT
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+		_, err = os.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"app/internal"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// operationOk is the result of an operation that succeeded
+	operationOk = "ok"
+	// operationRejected is the result of an operation answered with an error of the domain, e.g. a vehicle not found
+	operationRejected = "rejected"
+	// operationCanceled is the result of an operation given up because its caller went away, e.g. a client that disconnected
+	operationCanceled = "canceled"
+	// operationError is the result of an operation that failed, e.g. the database is not reachable or too slow
+	operationError = "error"
+	// collectTimeout is the maximum duration the vehicles are counted for on a scrape
+	collectTimeout = 5 * time.Second
+)
+
+// NewVehicleInstrumented is a function that returns a new instance of VehicleInstrumented
+func NewVehicleInstrumented(rp internal.VehicleRepository) *VehicleInstrumented {
+	return &VehicleInstrumented{
+		rp: rp,
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vehicle_repository_operations_total",
+			Help: "Number of operations of the vehicle repository, by operation and result (ok, rejected, canceled or error).",
+		}, []string{"operation", "result"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "vehicle_repository_operation_duration_seconds",
+			Help:    "Duration of the operations of the vehicle repository, by operation.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 9),
+		}, []string{"operation"}),
+		vehicles: prometheus.NewDesc(
+			"vehicle_repository_vehicles",
+			"Number of vehicles in the repository, by fuel type.",
+			[]string{"fuel_type"}, nil,
+		),
+	}
+}
+
+// VehicleInstrumented is a struct that represents a vehicle repository that records metrics of the one it decorates
+// - it is a prometheus.Collector of the count and the duration of every operation, by operation and result,
+// and of the number of vehicles by fuel type, read with CountByFuelType on every scrape
+type VehicleInstrumented struct {
+	// rp is the decorated repository
+	rp internal.VehicleRepository
+	// operations is the number of operations, by operation and result
+	operations *prometheus.CounterVec
+	// durations is the duration of the operations, by operation
+	durations *prometheus.HistogramVec
+	// vehicles describes the number of vehicles, by fuel type
+	vehicles *prometheus.Desc
+}
+
+// Describe is a method that sends the descriptors of the metrics of the repository
+func (r *VehicleInstrumented) Describe(ch chan<- *prometheus.Desc) {
+	r.operations.Describe(ch)
+	r.durations.Describe(ch)
+	ch <- r.vehicles
+}
+
+// Collect is a method that sends the metrics of the repository and the number of vehicles by fuel type
+// - the vehicles are counted by the decorated repository, not listed, and the count is not recorded as an operation
+// - every known fuel type is reported, with 0 if no vehicle uses it
+func (r *VehicleInstrumented) Collect(ch chan<- prometheus.Metric) {
+	r.operations.Collect(ch)
+	r.durations.Collect(ch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	v, err := r.rp.CountByFuelType(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(r.vehicles, err)
+		return
+	}
+	count := make(map[internal.FuelType]int, len(internal.FuelTypes))
+	for _, fuelType := range internal.FuelTypes {
+		count[fuelType] = 0
+	}
+	for fuelType, n := range v {
+		count[fuelType] = n
+	}
+	for fuelType, n := range count {
+		ch <- prometheus.MustNewConstMetric(r.vehicles, prometheus.GaugeValue, float64(n), string(fuelType))
+	}
+}
+
+// observe is a method that records an operation that started at start and returned *err
+func (r *VehicleInstrumented) observe(operation string, start time.Time, err *error) {
+	r.operations.WithLabelValues(operation, operationResult(*err)).Inc()
+	r.durations.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// operationResult is a function that returns the result label of an operation that returned err
+// - errors of the domain are the answer of the repository to the request, not a failure of it
+// - a canceled context is the caller giving up, not a failure either, while a deadline exceeded is the repository being too slow
+func operationResult(err error) string {
+	var validationErr *internal.VehicleValidationError
+	var batchErr *internal.VehicleBatchError
+	switch {
+	case err == nil:
+		return operationOk
+	case errors.As(err, &validationErr), errors.As(err, &batchErr):
+		return operationRejected
+	case errors.Is(err, context.Canceled):
+		return operationCanceled
+	}
+	switch err {
+	case internal.ErrVehicleNotFound, internal.ErrVehiclesNotFound, internal.ErrVehicleAlreadyExists,
+		internal.ErrVehicleMandatoryFields, internal.ErrVehicleInvalidMaxSpeed, internal.ErrVehicleInvalidFuelType,
+		internal.ErrVehicleInvalidTransmission, internal.ErrVehicleRegistrationAlreadyExists:
+		return operationRejected
+	default:
+		return operationError
+	}
+}
+
+// FindAll is a method that returns a map of all vehicles
+func (r *VehicleInstrumented) FindAll(ctx context.Context) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("FindAll", time.Now(), &err)
+
+	v, err = r.rp.FindAll(ctx)
+	return
+}
+
+// FindById is a method that returns a vehicle by id
+func (r *VehicleInstrumented) FindById(ctx context.Context, id int) (v internal.Vehicle, err error) {
+	defer r.observe("FindById", time.Now(), &err)
+
+	v, err = r.rp.FindById(ctx, id)
+	return
+}
+
+// FindByRegistration is a method that returns a vehicle by registration (case insensitive)
+func (r *VehicleInstrumented) FindByRegistration(ctx context.Context, registration string) (v internal.Vehicle, err error) {
+	defer r.observe("FindByRegistration", time.Now(), &err)
+
+	v, err = r.rp.FindByRegistration(ctx, registration)
+	return
+}
+
+// Create is a method that adds a vehicle to the repository
+func (r *VehicleInstrumented) Create(ctx context.Context, v *internal.Vehicle) (err error) {
+	defer r.observe("Create", time.Now(), &err)
+
+	err = r.rp.Create(ctx, v)
+	return
+}
+
+// BatchCreate is a method that adds a list of vehicles to the repository
+func (r *VehicleInstrumented) BatchCreate(ctx context.Context, v []*internal.Vehicle) (err error) {
+	defer r.observe("BatchCreate", time.Now(), &err)
+
+	err = r.rp.BatchCreate(ctx, v)
+	return
+}
+
+// FindByColorAndYear is a method that returns a map of vehicles that match color and year
+func (r *VehicleInstrumented) FindByColorAndYear(ctx context.Context, color string, year int) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("FindByColorAndYear", time.Now(), &err)
+
+	v, err = r.rp.FindByColorAndYear(ctx, color, year)
+	return
+}
+
+// Delete is a method that deletes a vehicle from the repository
+func (r *VehicleInstrumented) Delete(ctx context.Context, id int) (err error) {
+	defer r.observe("Delete", time.Now(), &err)
+
+	err = r.rp.Delete(ctx, id)
+	return
+}
+
+// UpdateFuelType is a method that updates the fuel type of a vehicle in the repository
+func (r *VehicleInstrumented) UpdateFuelType(ctx context.Context, id int, fuelType internal.FuelType) (err error) {
+	defer r.observe("UpdateFuelType", time.Now(), &err)
+
+	err = r.rp.UpdateFuelType(ctx, id, fuelType)
+	return
+}
+
+// FindByWeightRange is a method that returns a map of vehicles that match weight range
+func (r *VehicleInstrumented) FindByWeightRange(ctx context.Context, minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("FindByWeightRange", time.Now(), &err)
+
+	v, err = r.rp.FindByWeightRange(ctx, minWeight, maxWeight)
+	return
+}
+
+// FindByBrandAndYearRange is a method that returns a map of vehicles that match brand and year range
+func (r *VehicleInstrumented) FindByBrandAndYearRange(ctx context.Context, brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("FindByBrandAndYearRange", time.Now(), &err)
+
+	v, err = r.rp.FindByBrandAndYearRange(ctx, brand, minYear, maxYear)
+	return
+}
+
+// FindAverageMaxSpeedByBrand is a method that returns the average max speed of the vehicles of a brand
+func (r *VehicleInstrumented) FindAverageMaxSpeedByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	defer r.observe("FindAverageMaxSpeedByBrand", time.Now(), &err)
+
+	avg, err = r.rp.FindAverageMaxSpeedByBrand(ctx, brand)
+	return
+}
+
+// UpdateMaxSpeed is a method that updates the max speed of a vehicle in the repository
+func (r *VehicleInstrumented) UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error) {
+	defer r.observe("UpdateMaxSpeed", time.Now(), &err)
+
+	err = r.rp.UpdateMaxSpeed(ctx, id, maxSpeed)
+	return
+}
+
+// FindByFuelType is a method that returns a map of vehicles that match fuel type
+func (r *VehicleInstrumented) FindByFuelType(ctx context.Context, fuelType string) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("FindByFuelType", time.Now(), &err)
+
+	v, err = r.rp.FindByFuelType(ctx, fuelType)
+	return
+}
+
+// CountByFuelType is a method that returns the number of vehicles of each fuel type
+func (r *VehicleInstrumented) CountByFuelType(ctx context.Context) (count map[internal.FuelType]int, err error) {
+	defer r.observe("CountByFuelType", time.Now(), &err)
+
+	count, err = r.rp.CountByFuelType(ctx)
+	return
+}
+
+// FindByTransmission is a method that returns a map of vehicles that match transmission
+func (r *VehicleInstrumented) FindByTransmission(ctx context.Context, transmission string) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("FindByTransmission", time.Now(), &err)
+
+	v, err = r.rp.FindByTransmission(ctx, transmission)
+	return
+}
+
+// FindAverageCapacityByBrand is a method that returns the average capacity of the vehicles of a brand
+func (r *VehicleInstrumented) FindAverageCapacityByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	defer r.observe("FindAverageCapacityByBrand", time.Now(), &err)
+
+	avg, err = r.rp.FindAverageCapacityByBrand(ctx, brand)
+	return
+}
+
+// FindByDimensions is a method that returns a map of vehicles that match length and width ranges
+func (r *VehicleInstrumented) FindByDimensions(ctx context.Context, minLength, maxLength, minWidth, maxWidth float64) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("FindByDimensions", time.Now(), &err)
+
+	v, err = r.rp.FindByDimensions(ctx, minLength, maxLength, minWidth, maxWidth)
+	return
+}
+
+// Find is a method that returns a map of vehicles that match every filter of the query
+func (r *VehicleInstrumented) Find(ctx context.Context, q internal.VehicleQuery) (v map[int]internal.Vehicle, err error) {
+	defer r.observe("Find", time.Now(), &err)
+
+	v, err = r.rp.Find(ctx, q)
+	return
+}
+
+// FindPage is a method that returns the page of the vehicles that match every filter of the query, in its order
+func (r *VehicleInstrumented) FindPage(ctx context.Context, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	defer r.observe("FindPage", time.Now(), &err)
+
+	p, err = r.rp.FindPage(ctx, q)
+	return
+}
+
+// Update is a method that replaces every attribute of a vehicle
+func (r *VehicleInstrumented) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	defer r.observe("Update", time.Now(), &err)
+
+	err = r.rp.Update(ctx, v)
+	return
+}
+
+// Patch is a method that applies a change to a vehicle and stores the result if it is valid
+func (r *VehicleInstrumented) Patch(ctx context.Context, id int, patch func(v *internal.Vehicle) (err error)) (v internal.Vehicle, err error) {
+	defer r.observe("Patch", time.Now(), &err)
+
+	v, err = r.rp.Patch(ctx, id, patch)
+	return
+}
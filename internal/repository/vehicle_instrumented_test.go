@@ -0,0 +1,89 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"app/internal/repository/repotest"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVehicleInstrumented(t *testing.T) {
+	t.Run("should count the operations by result and the vehicles by fuel type", func(t *testing.T) {
+		// ARRANGE
+		rp := repository.NewVehicleInstrumented(repository.NewVehicleMap(repotest.Fixtures()))
+
+		// ACT
+		_, err := rp.FindById(context.Background(), 1)
+		require.NoError(t, err)
+		_, err = rp.FindById(context.Background(), 99)
+		require.ErrorIs(t, err, internal.ErrVehicleNotFound)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = rp.Delete(ctx, 1)
+		require.ErrorIs(t, err, context.Canceled)
+		ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		err = rp.Delete(ctx, 1)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// ASSERT
+		expected := `
+# HELP vehicle_repository_operations_total Number of operations of the vehicle repository, by operation and result (ok, rejected, canceled or error).
+# TYPE vehicle_repository_operations_total counter
+vehicle_repository_operations_total{operation="Delete",result="canceled"} 1
+vehicle_repository_operations_total{operation="Delete",result="error"} 1
+vehicle_repository_operations_total{operation="FindById",result="ok"} 1
+vehicle_repository_operations_total{operation="FindById",result="rejected"} 1
+# HELP vehicle_repository_vehicles Number of vehicles in the repository, by fuel type.
+# TYPE vehicle_repository_vehicles gauge
+vehicle_repository_vehicles{fuel_type="biodiesel"} 0
+vehicle_repository_vehicles{fuel_type="diesel"} 1
+vehicle_repository_vehicles{fuel_type="electric"} 0
+vehicle_repository_vehicles{fuel_type="gas"} 0
+vehicle_repository_vehicles{fuel_type="gasoline"} 2
+vehicle_repository_vehicles{fuel_type="hybrid"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(rp, strings.NewReader(expected), "vehicle_repository_operations_total", "vehicle_repository_vehicles"))
+		require.Equal(t, 2, testutil.CollectAndCount(rp, "vehicle_repository_operation_duration_seconds"))
+	})
+
+	t.Run("should count the vehicles by fuel type without listing them", func(t *testing.T) {
+		// ARRANGE
+		rp := repository.NewVehicleInstrumented(unlisted{repository.NewVehicleMap(repotest.Fixtures())})
+		require.NoError(t, rp.Delete(context.Background(), 2))
+
+		// ACT
+		expected := `
+# HELP vehicle_repository_vehicles Number of vehicles in the repository, by fuel type.
+# TYPE vehicle_repository_vehicles gauge
+vehicle_repository_vehicles{fuel_type="biodiesel"} 0
+vehicle_repository_vehicles{fuel_type="diesel"} 0
+vehicle_repository_vehicles{fuel_type="electric"} 0
+vehicle_repository_vehicles{fuel_type="gas"} 0
+vehicle_repository_vehicles{fuel_type="gasoline"} 2
+vehicle_repository_vehicles{fuel_type="hybrid"} 0
+`
+		err := testutil.CollectAndCompare(rp, strings.NewReader(expected), "vehicle_repository_vehicles")
+
+		// ASSERT
+		require.NoError(t, err)
+	})
+}
+
+// unlisted is a struct that represents a vehicle repository that fails to list every vehicle
+type unlisted struct {
+	*repository.VehicleMap
+}
+
+// FindAll is a method that fails, a scrape must not list every vehicle
+func (unlisted) FindAll(ctx context.Context) (v map[int]internal.Vehicle, err error) {
+	err = errors.New("every vehicle listed")
+	return
+}
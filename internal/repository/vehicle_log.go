@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"app/internal"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+const (
+	// logOpPut is the operation of a log record that creates or replaces vehicles
+	logOpPut = "put"
+	// logOpDelete is the operation of a log record that deletes vehicles
+	logOpDelete = "delete"
+)
+
+var (
+	// ErrLogRecordCorrupted is an error that represents a log record that is incomplete or does not match its checksum
+	ErrLogRecordCorrupted = errors.New("log record corrupted")
+)
+
+// logRecord is a struct that represents a record of the log
+// - records hold the resulting state of the vehicles, so replaying them is idempotent
+type logRecord struct {
+	// Op is the operation of the record (logOpPut or logOpDelete)
+	Op string `json:"op"`
+	// Vehicles is the list of vehicles to put
+	Vehicles []internal.Vehicle `json:"vehicles,omitempty"`
+	// Ids is the list of vehicle ids to delete
+	Ids []int `json:"ids,omitempty"`
+}
+
+// NewVehicleLog is a function that returns a new instance of VehicleLog
+// - db is the last snapshot of the vehicles, the records of the log at path are replayed on top of it
+// - sv is the saver used to write a new snapshot on compaction
+// - compactEvery is the number of records after which the log is compacted, 0 or less disables compaction
+func NewVehicleLog(db map[int]internal.Vehicle, sv internal.VehicleSaver, path string, compactEvery int) (r *VehicleLog, err error) {
+	r = &VehicleLog{
+		VehicleMap:   NewVehicleMap(db),
+		sv:           sv,
+		compactEvery: compactEvery,
+	}
+	r.VehicleMap.onCommit = r.record
+
+	// open log
+	r.file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return
+	}
+
+	// replay log
+	err = r.replay()
+	if err != nil {
+		r.file.Close()
+		return
+	}
+
+	return
+}
+
+// VehicleLog is a struct that represents a vehicle repository that records every change in an append-only log
+// - queries and mutations are served by the embedded VehicleMap, which appends the record of every change through record
+// before applying it in place
+// - the write lock of VehicleMap orders the records as the changes, compaction holds its read lock
+type VehicleLog struct {
+	*VehicleMap
+	// mu guards the log file and its counters, it is always acquired after the lock of VehicleMap
+	mu sync.Mutex
+	// sv is the saver used to write a new snapshot on compaction
+	sv internal.VehicleSaver
+	// file is the log file, positioned at its end
+	file *os.File
+	// size is the size of the log file up to its last complete record
+	size int64
+	// records is the number of records in the log
+	records int
+	// compactEvery is the number of records after which the log is compacted
+	compactEvery int
+}
+
+// replay is a method that applies the records of the log to the repository
+// - an incomplete or corrupted final record is a torn write: it is discarded and the log is truncated before it
+// - a corrupted record followed by other records fails the replay, as dropping them would silently lose changes
+func (r *VehicleLog) replay() (err error) {
+	reader := bufio.NewReader(r.file)
+	var offset int64
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			err = nil
+			break
+		}
+		if err != nil && err != io.EOF {
+			return
+		}
+
+		var rec logRecord
+		rec, err = decodeLogRecord(line)
+		if err != nil {
+			if _, perr := reader.Peek(1); perr != io.EOF {
+				err = fmt.Errorf("%w: record %d at offset %d", err, r.records+1, offset)
+				return
+			}
+			// torn write: discard the tail of the log
+			err = nil
+			break
+		}
+		r.apply(rec)
+		r.records++
+		offset += int64(len(line))
+	}
+
+	// drop anything after the last valid record and position at the end
+	err = r.file.Truncate(offset)
+	if err != nil {
+		return
+	}
+	_, err = r.file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return
+	}
+	r.size = offset
+	return
+}
+
+// apply is a method that applies a record to the embedded VehicleMap
+func (r *VehicleLog) apply(rec logRecord) {
+	r.VehicleMap.mu.Lock()
+	defer r.VehicleMap.mu.Unlock()
+
+	switch rec.Op {
+	case logOpPut:
+		for _, v := range rec.Vehicles {
+			r.VehicleMap.put(v)
+		}
+	case logOpDelete:
+		for _, id := range rec.Ids {
+			r.VehicleMap.remove(id)
+		}
+	}
+}
+
+// encodeLogRecord is a function that serializes a record as a line with the format "{crc32} {json}\n"
+func encodeLogRecord(rec logRecord) (line []byte, err error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	line = []byte(fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload))
+	return
+}
+
+// decodeLogRecord is a function that deserializes a line with the format "{crc32} {json}\n"
+func decodeLogRecord(line []byte) (rec logRecord, err error) {
+	// a record is only complete once its line break is written
+	if len(line) < 10 || line[len(line)-1] != '\n' || line[8] != ' ' {
+		err = ErrLogRecordCorrupted
+		return
+	}
+	var checksum uint32
+	_, err = fmt.Sscanf(string(line[:8]), "%08x", &checksum)
+	if err != nil {
+		err = ErrLogRecordCorrupted
+		return
+	}
+	payload := bytes.TrimSuffix(line[9:], []byte("\n"))
+	if crc32.ChecksumIEEE(payload) != checksum {
+		err = ErrLogRecordCorrupted
+		return
+	}
+	err = json.Unmarshal(payload, &rec)
+	if err != nil {
+		err = ErrLogRecordCorrupted
+		return
+	}
+	return
+}
+
+// record is a method that appends the record of a change to the log, called by the embedded VehicleMap under its write lock
+// - the change is applied in place once the record is synced, if the append fails the repository is left as it was
+func (r *VehicleLog) record(put []internal.Vehicle, remove []int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := logRecord{Op: logOpPut, Vehicles: put}
+	if len(remove) > 0 {
+		rec = logRecord{Op: logOpDelete, Ids: remove}
+	}
+	line, err := encodeLogRecord(rec)
+	if err != nil {
+		return
+	}
+	err = r.append(line)
+	if err != nil {
+		return
+	}
+	r.records++
+	return
+}
+
+// append is a method that writes a line at the end of the log and syncs it
+// - on failure the log is truncated back to its last complete record, so a partial line never precedes later records
+func (r *VehicleLog) append(line []byte) (err error) {
+	_, err = r.file.Write(line)
+	if err == nil {
+		err = r.file.Sync()
+	}
+	if err != nil {
+		if terr := r.file.Truncate(r.size); terr != nil {
+			err = errors.Join(err, terr)
+		} else if _, serr := r.file.Seek(r.size, io.SeekStart); serr != nil {
+			err = errors.Join(err, serr)
+		}
+		return
+	}
+	r.size += int64(len(line))
+	return
+}
+
+// compactIfDue is a method that compacts the log once it holds compactEvery records
+// - the write before it already succeeded, so a failed compaction is only logged and retried after the next write
+func (r *VehicleLog) compactIfDue() {
+	r.VehicleMap.mu.RLock()
+	defer r.VehicleMap.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.compactEvery <= 0 || r.records < r.compactEvery {
+		return
+	}
+	if err := r.compact(); err != nil {
+		log.Printf("vehicle log: compaction of %d records failed, retrying after the next write: %v", r.records, err)
+	}
+}
+
+// compact is a method that saves a snapshot of the vehicles and empties the log, without locking
+// - the caller holds at least the read lock of the embedded VehicleMap, so no record is appended in between
+// - if the process stops between both steps, replaying the log on top of the new snapshot is harmless
+func (r *VehicleLog) compact() (err error) {
+	err = r.sv.Save(r.VehicleMap.db)
+	if err != nil {
+		return
+	}
+	err = r.file.Truncate(0)
+	if err != nil {
+		return
+	}
+	_, err = r.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+	r.records = 0
+	r.size = 0
+	return
+}
+
+// Compact is a method that saves a snapshot of the vehicles and empties the log
+func (r *VehicleLog) Compact() (err error) {
+	r.VehicleMap.mu.RLock()
+	defer r.VehicleMap.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.compact()
+	return
+}
+
+// Close is a method that closes the log
+func (r *VehicleLog) Close() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.file.Close()
+	return
+}
+
+// Create is a method that adds a vehicle to the repository
+func (r *VehicleLog) Create(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = r.VehicleMap.Create(ctx, v)
+	if err == nil {
+		r.compactIfDue()
+	}
+	return
+}
+
+// BatchCreate is a method that adds a list of vehicles to the repository
+func (r *VehicleLog) BatchCreate(ctx context.Context, v []*internal.Vehicle) (err error) {
+	err = r.VehicleMap.BatchCreate(ctx, v)
+	if err == nil {
+		r.compactIfDue()
+	}
+	return
+}
+
+// Delete is a method that deletes a vehicle from the repository
+func (r *VehicleLog) Delete(ctx context.Context, id int) (err error) {
+	err = r.VehicleMap.Delete(ctx, id)
+	if err == nil {
+		r.compactIfDue()
+	}
+	return
+}
+
+// UpdateFuelType is a method that updates the fuel type of a vehicle in the repository
+func (r *VehicleLog) UpdateFuelType(ctx context.Context, id int, fuelType internal.FuelType) (err error) {
+	err = r.VehicleMap.UpdateFuelType(ctx, id, fuelType)
+	if err == nil {
+		r.compactIfDue()
+	}
+	return
+}
+
+// UpdateMaxSpeed is a method that updates the max speed of a vehicle in the repository
+func (r *VehicleLog) UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error) {
+	err = r.VehicleMap.UpdateMaxSpeed(ctx, id, maxSpeed)
+	if err == nil {
+		r.compactIfDue()
+	}
+	return
+}
+
+// Update is a method that replaces every attribute of a vehicle
+func (r *VehicleLog) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = r.VehicleMap.Update(ctx, v)
+	if err == nil {
+		r.compactIfDue()
+	}
+	return
+}
+
+// Patch is a method that applies a change to a vehicle and stores the result if it is valid
+func (r *VehicleLog) Patch(ctx context.Context, id int, patch func(v *internal.Vehicle) (err error)) (v internal.Vehicle, err error) {
+	v, err = r.VehicleMap.Patch(ctx, id, patch)
+	if err == nil {
+		r.compactIfDue()
+	}
+	return
+}
@@ -0,0 +1,241 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"app/internal/repository"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newVehicleLogFixture is a function that returns the snapshot and log paths of a fresh store with one vehicle
+func newVehicleLogFixture(t *testing.T) (snapshotPath, logPath string) {
+	dir := t.TempDir()
+	snapshotPath = filepath.Join(dir, "vehicles.json")
+	logPath = filepath.Join(dir, "vehicles.json.log")
+	err := os.WriteFile(snapshotPath, []byte(`[{"id":1,"brand":"Toyota","model":"Corolla","registration":"ABC-1234","color":"Blue","year":2020,"passengers":5,"max_speed":180,"fuel_type":"gasoline","transmission":"automatic","weight":1300,"height":1.45,"length":4.62,"width":1.77}]`), 0o644)
+	require.NoError(t, err)
+	return
+}
+
+// openVehicleLog is a function that loads the snapshot and replays the log on top of it, as the application does on startup
+func openVehicleLog(t *testing.T, snapshotPath, logPath string, compactEvery int) *repository.VehicleLog {
+	ld := loader.NewVehicleJSONFile(snapshotPath)
+	db, err := ld.Load()
+	require.NoError(t, err)
+	rp, err := repository.NewVehicleLog(db, ld, logPath, compactEvery)
+	require.NoError(t, err)
+	t.Cleanup(func() { rp.Close() })
+	return rp
+}
+
+func TestVehicleLog_Replay(t *testing.T) {
+	t.Run("should restore every mutation after a restart", func(t *testing.T) {
+		// ARRANGE
+		snapshotPath, logPath := newVehicleLogFixture(t)
+		rp := openVehicleLog(t, snapshotPath, logPath, 0)
+		require.NoError(t, rp.Create(context.Background(), internal.NewVehicle(2, "Ford", "Fiesta", "DEF-5678", "Red", 2019, 5, 170.0, "diesel", "manual", 1100.0, 1.4, 4.0, 1.7)))
+		require.NoError(t, rp.BatchCreate(context.Background(), []*internal.Vehicle{
+			internal.NewVehicle(3, "Ford", "Focus", "GHI-9012", "Black", 2018, 5, 190.0, "diesel", "manual", 1200.0, 1.4, 4.3, 1.8),
+		}))
+		require.NoError(t, rp.UpdateFuelType(context.Background(), 1, "diesel"))
+		require.NoError(t, rp.UpdateMaxSpeed(context.Background(), 3, 200.0))
+		require.NoError(t, rp.Update(context.Background(), internal.NewVehicle(1, "Toyota", "Corolla", "ABC-1234", "White", 2021, 5, 185.0, "hybrid", "automatic", 1350.0, 1.45, 4.62, 1.77)))
+		_, err := rp.Patch(context.Background(), 3, func(v *internal.Vehicle) error {
+			v.Color = "Grey"
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, rp.Delete(context.Background(), 2))
+		expected, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, rp.Close())
+
+		// ACT
+		restored := openVehicleLog(t, snapshotPath, logPath, 0)
+
+		// ASSERT
+		v, err := restored.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+
+	t.Run("should restore the last consistent state when the log is truncated mid-record", func(t *testing.T) {
+		// ARRANGE
+		snapshotPath, logPath := newVehicleLogFixture(t)
+		rp := openVehicleLog(t, snapshotPath, logPath, 0)
+		require.NoError(t, rp.Create(context.Background(), internal.NewVehicle(2, "Ford", "Fiesta", "DEF-5678", "Red", 2019, 5, 170.0, "diesel", "manual", 1100.0, 1.4, 4.0, 1.7)))
+		expected, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		info, err := os.Stat(logPath)
+		require.NoError(t, err)
+		require.NoError(t, rp.Create(context.Background(), internal.NewVehicle(3, "Ford", "Focus", "GHI-9012", "Black", 2018, 5, 190.0, "diesel", "manual", 1200.0, 1.4, 4.3, 1.8)))
+		require.NoError(t, rp.Close())
+
+		// - simulate a crash in the middle of the last record
+		full, err := os.Stat(logPath)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(logPath, info.Size()+(full.Size()-info.Size())/2))
+
+		// ACT
+		restored := openVehicleLog(t, snapshotPath, logPath, 0)
+
+		// ASSERT
+		// - the torn record is discarded
+		v, err := restored.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+		// - the log is truncated so new records are readable after another restart
+		require.NoError(t, restored.Create(context.Background(), internal.NewVehicle(4, "Ford", "Ka", "JKL-3456", "White", 2017, 4, 160.0, "gasoline", "manual", 900.0, 1.4, 3.9, 1.6)))
+		require.NoError(t, restored.Close())
+		v, err = openVehicleLog(t, snapshotPath, logPath, 0).FindAll(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, v, 4)
+		require.NotContains(t, v, 3)
+	})
+
+	t.Run("should discard a final record whose checksum does not match", func(t *testing.T) {
+		// ARRANGE
+		snapshotPath, logPath := newVehicleLogFixture(t)
+		rp := openVehicleLog(t, snapshotPath, logPath, 0)
+		require.NoError(t, rp.Delete(context.Background(), 1))
+		require.NoError(t, rp.Close())
+
+		// - flip a byte of the payload
+		data, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		data[len(data)-3] ^= 0xff
+		require.NoError(t, os.WriteFile(logPath, data, 0o644))
+
+		// ACT
+		restored := openVehicleLog(t, snapshotPath, logPath, 0)
+
+		// ASSERT
+		v, err := restored.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, v, 1)
+	})
+
+	t.Run("should fail when a record followed by other records is corrupted", func(t *testing.T) {
+		// ARRANGE
+		snapshotPath, logPath := newVehicleLogFixture(t)
+		rp := openVehicleLog(t, snapshotPath, logPath, 0)
+		require.NoError(t, rp.UpdateFuelType(context.Background(), 1, "diesel"))
+		info, err := os.Stat(logPath)
+		require.NoError(t, err)
+		require.NoError(t, rp.Delete(context.Background(), 1))
+		require.NoError(t, rp.Close())
+
+		// - flip a byte of the payload of the first record
+		data, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		data[info.Size()-3] ^= 0xff
+		require.NoError(t, os.WriteFile(logPath, data, 0o644))
+		db, err := loader.NewVehicleJSONFile(snapshotPath).Load()
+		require.NoError(t, err)
+
+		// ACT
+		_, err = repository.NewVehicleLog(db, loader.NewVehicleJSONFile(snapshotPath), logPath, 0)
+
+		// ASSERT
+		require.ErrorIs(t, err, repository.ErrLogRecordCorrupted)
+		require.ErrorContains(t, err, "record 1 at offset 0")
+		// - the log is left untouched
+		after, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		require.Equal(t, data, after)
+	})
+}
+
+func TestVehicleLog_Persistence(t *testing.T) {
+	t.Run("should leave the repository unchanged when the record can not be appended", func(t *testing.T) {
+		// ARRANGE
+		snapshotPath, logPath := newVehicleLogFixture(t)
+		rp := openVehicleLog(t, snapshotPath, logPath, 0)
+		expected, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		// - writes to a closed log fail
+		require.NoError(t, rp.Close())
+
+		// ACT
+		err = rp.UpdateFuelType(context.Background(), 1, "diesel")
+
+		// ASSERT
+		require.ErrorIs(t, err, os.ErrClosed)
+		v, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+}
+
+func TestVehicleLog_Compact(t *testing.T) {
+	t.Run("should write a snapshot and empty the log every compactEvery records", func(t *testing.T) {
+		// ARRANGE
+		snapshotPath, logPath := newVehicleLogFixture(t)
+		rp := openVehicleLog(t, snapshotPath, logPath, 2)
+
+		// ACT
+		require.NoError(t, rp.Create(context.Background(), internal.NewVehicle(2, "Ford", "Fiesta", "DEF-5678", "Red", 2019, 5, 170.0, "diesel", "manual", 1100.0, 1.4, 4.0, 1.7)))
+		require.NoError(t, rp.UpdateFuelType(context.Background(), 2, "gasoline"))
+
+		// ASSERT
+		// - the log is empty
+		info, err := os.Stat(logPath)
+		require.NoError(t, err)
+		require.Zero(t, info.Size())
+		// - the snapshot holds every change
+		expected, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		v, err := loader.NewVehicleJSONFile(snapshotPath).Load()
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+
+	t.Run("should keep the write and retry after the next one when the compaction fails", func(t *testing.T) {
+		// ARRANGE
+		// - saver that fails until it is fixed
+		snapshotPath, logPath := newVehicleLogFixture(t)
+		ld := loader.NewVehicleJSONFile(snapshotPath)
+		db, err := ld.Load()
+		require.NoError(t, err)
+		var saveErr error = errors.New("disk full")
+		sv := saverFunc(func(v map[int]internal.Vehicle) error {
+			if saveErr != nil {
+				return saveErr
+			}
+			return ld.Save(v)
+		})
+		rp, err := repository.NewVehicleLog(db, sv, logPath, 1)
+		require.NoError(t, err)
+		t.Cleanup(func() { rp.Close() })
+
+		// ACT
+		err = rp.UpdateFuelType(context.Background(), 1, "diesel")
+
+		// ASSERT
+		// - the write succeeded, it is served and kept in the log
+		require.NoError(t, err)
+		v, err := rp.FindById(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, internal.FuelTypeDiesel, v.FuelType)
+		info, err := os.Stat(logPath)
+		require.NoError(t, err)
+		require.NotZero(t, info.Size())
+		// - the next write compacts once the saver works again
+		saveErr = nil
+		require.NoError(t, rp.UpdateMaxSpeed(context.Background(), 1, 200.0))
+		info, err = os.Stat(logPath)
+		require.NoError(t, err)
+		require.Zero(t, info.Size())
+		expected, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		snapshot, err := loader.NewVehicleJSONFile(snapshotPath).Load()
+		require.NoError(t, err)
+		require.Equal(t, expected, snapshot)
+	})
+}
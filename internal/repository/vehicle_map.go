@@ -1,25 +1,104 @@
 package repository
 
-import "app/internal"
+import (
+	"app/internal"
+	"context"
+	"strings"
+	"sync"
+)
+
+// scanCheckEvery is the number of vehicles a full scan visits between checks of its context
+const scanCheckEvery = 256
 
 // NewVehicleMap is a function that returns a new instance of VehicleMap
+// - db is trusted as it is, registrations must be unique: the loaders reject files where they are not, see internal.FindRegistrationConflicts
 func NewVehicleMap(db map[int]internal.Vehicle) *VehicleMap {
 	// default db
 	defaultDb := make(map[int]internal.Vehicle)
 	if db != nil {
 		defaultDb = db
 	}
-	return &VehicleMap{db: defaultDb}
+	r := &VehicleMap{db: defaultDb, ix: newVehicleIndex(defaultDb)}
+	for id := range defaultDb {
+		r.lastId = max(r.lastId, id)
+	}
+	return r
 }
 
 // VehicleMap is a struct that represents a vehicle repository
 type VehicleMap struct {
+	// mu guards db and ix, since handlers are served concurrently
+	mu sync.RWMutex
 	// db is a map of vehicles
 	db map[int]internal.Vehicle
+	// ix is the set of secondary indexes of db, kept up to date by put and remove
+	ix *vehicleIndex
+	// lastId is the highest id stored so far, omitted ids are allocated after it so that they are never reused
+	lastId int
+	// onCommit is called under the write lock with the vehicles a mutation puts and the ids it removes, before they are applied
+	// - repositories that persist the changes set it, if it fails the mutation is not applied
+	onCommit func(put []internal.Vehicle, remove []int) (err error)
+}
+
+// commit is a method that persists a change through onCommit, if set, and then applies it, without locking
+func (r *VehicleMap) commit(put []internal.Vehicle, remove []int) (err error) {
+	if r.onCommit != nil {
+		err = r.onCommit(put, remove)
+		if err != nil {
+			return
+		}
+	}
+	for _, v := range put {
+		r.put(v)
+	}
+	for _, id := range remove {
+		r.remove(id)
+	}
+	return
+}
+
+// put is a method that creates or replaces a vehicle and updates the indexes, without locking
+func (r *VehicleMap) put(v internal.Vehicle) {
+	if old, ok := r.db[v.Id]; ok {
+		r.ix.remove(old)
+	}
+	r.db[v.Id] = v
+	r.ix.add(v)
+	r.lastId = max(r.lastId, v.Id)
+}
+
+// remove is a method that deletes a vehicle and updates the indexes, without locking
+func (r *VehicleMap) remove(id int) {
+	if old, ok := r.db[id]; ok {
+		r.ix.remove(old)
+		delete(r.db, id)
+	}
+}
+
+// collect is a method that returns the vehicles with the given ids, without locking
+func (r *VehicleMap) collect(ids []int) (v map[int]internal.Vehicle) {
+	v = make(map[int]internal.Vehicle, len(ids))
+	for _, id := range ids {
+		v[id] = r.db[id]
+	}
+	return
+}
+
+// registrationTaken is a method that returns whether another vehicle has the registration of v, without locking
+func (r *VehicleMap) registrationTaken(v *internal.Vehicle) bool {
+	id, ok := r.ix.registration(v.Registration)
+	return ok && id != v.Id
 }
 
 // FindAll is a method that returns a map of all vehicles
-func (r *VehicleMap) FindAll() (v map[int]internal.Vehicle, err error) {
+func (r *VehicleMap) FindAll(ctx context.Context) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	v = make(map[int]internal.Vehicle)
 
 	// copy db
@@ -30,42 +109,165 @@ func (r *VehicleMap) FindAll() (v map[int]internal.Vehicle, err error) {
 	return
 }
 
+// FindById is a method that returns a vehicle by id
+func (r *VehicleMap) FindById(ctx context.Context, id int) (v internal.Vehicle, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	v, ok := r.find(id)
+	if !ok {
+		err = internal.ErrVehicleNotFound
+	}
+	return
+}
+
+// FindByRegistration is a method that returns a vehicle by registration (case insensitive)
+func (r *VehicleMap) FindByRegistration(ctx context.Context, registration string) (v internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	id, ok := r.ix.registration(registration)
+	if !ok {
+		err = internal.ErrVehicleNotFound
+		return
+	}
+	v = r.db[id]
+	return
+}
+
 // Create is a method that adds a vehicle to the repository
-func (r *VehicleMap) Create(v *internal.Vehicle) (err error) {
-	err = ValidateVehicleMandatoryFields(v)
+// - if the id is omitted (0) the next one is allocated and set on v
+func (r *VehicleMap) Create(ctx context.Context, v *internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = r.create(v)
+	return
+}
+
+// create is a method that adds a vehicle to the repository without locking
+func (r *VehicleMap) create(v *internal.Vehicle) (err error) {
+	vehicle := *v
+	if vehicle.Id == 0 {
+		vehicle.Id = r.lastId + 1
+	}
+
+	err = ValidateVehicle(&vehicle)
 	if err != nil {
 		return
 	}
-	if _, ok := r.db[v.Id]; ok {
+	if _, ok := r.db[vehicle.Id]; ok {
 		err = internal.ErrVehicleAlreadyExists
 		return
 	}
-	r.db[v.Id] = *v
+	if r.registrationTaken(&vehicle) {
+		err = internal.ErrVehicleRegistrationAlreadyExists
+		return
+	}
+	err = r.commit([]internal.Vehicle{vehicle}, nil)
+	if err != nil {
+		return
+	}
+	v.Id = vehicle.Id
+	return
+}
+
+// find is a method that returns a vehicle by id
+func (r *VehicleMap) find(id int) (v internal.Vehicle, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok = r.db[id]
 	return
 }
 
 // BatchCreate is a method that adds a list of vehicles to the repository
-func (r *VehicleMap) BatchCreate(v []*internal.Vehicle) (err error) {
+// - either all vehicles are created or none of them, in which case a *internal.VehicleBatchError is returned
+// - the omitted ids (0) are allocated after the highest id of the repository and of the batch, and set on v
+func (r *VehicleMap) BatchCreate(ctx context.Context, v []*internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	// allocate the omitted ids
+	lastId := r.lastId
 	for _, vehicle := range v {
-		err = r.Create(vehicle)
-		if err != nil {
-			return
+		lastId = max(lastId, vehicle.Id)
+	}
+	vehicles := make([]internal.Vehicle, len(v))
+	for i, vehicle := range v {
+		vehicles[i] = *vehicle
+		if vehicles[i].Id == 0 {
+			lastId++
+			vehicles[i].Id = lastId
 		}
 	}
+
+	// validate every vehicle before creating any
+	batchErr := &internal.VehicleBatchError{}
+	seen := make(map[int]bool)
+	seenRegistrations := make(map[string]bool)
+	for i := range vehicles {
+		vehicle := &vehicles[i]
+		itemErr := ValidateVehicle(vehicle)
+		if itemErr == nil {
+			if _, ok := r.db[vehicle.Id]; ok {
+				itemErr = internal.ErrVehicleAlreadyExists
+			} else if seen[vehicle.Id] {
+				itemErr = internal.ErrVehicleDuplicatedInBatch
+			} else if r.registrationTaken(vehicle) {
+				itemErr = internal.ErrVehicleRegistrationAlreadyExists
+			} else if seenRegistrations[registrationKey(vehicle.Registration)] {
+				itemErr = internal.ErrVehicleRegistrationDuplicatedInBatch
+			}
+		}
+		if itemErr != nil {
+			batchErr.Items = append(batchErr.Items, internal.VehicleBatchItemError{Index: i, Id: v[i].Id, Err: itemErr})
+			continue
+		}
+		seen[vehicle.Id] = true
+		seenRegistrations[registrationKey(vehicle.Registration)] = true
+	}
+	if len(batchErr.Items) > 0 {
+		err = batchErr
+		return
+	}
+
+	// create vehicles
+	err = r.commit(vehicles, nil)
+	if err != nil {
+		return
+	}
+	for i, vehicle := range vehicles {
+		v[i].Id = vehicle.Id
+	}
 	return
 }
 
 // FindByColorAndYear is a method that returns a map of vehicles that match color and year
-func (r *VehicleMap) FindByColorAndYear(color string, year int) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+func (r *VehicleMap) FindByColorAndYear(ctx context.Context, color string, year int) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	// Search in db
-	for key, value := range r.db {
-		if value.Color == color && value.FabricationYear == year {
-			v[key] = value
-		}
+	if err = ctx.Err(); err != nil {
+		return
 	}
 
+	// Search in index
+	v = r.collect(r.ix.colorAndYear(color, year))
+
 	if len(v) == 0 {
 		err = internal.ErrVehiclesNotFound
 	}
@@ -74,34 +276,147 @@ func (r *VehicleMap) FindByColorAndYear(color string, year int) (v map[int]inter
 }
 
 // Delete is a method that deletes a vehicle from the repository
-func (r *VehicleMap) Delete(id int) (err error) {
+func (r *VehicleMap) Delete(ctx context.Context, id int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	if _, ok := r.db[id]; !ok {
 		err = internal.ErrVehicleNotFound
 		return
 	}
-	delete(r.db, id)
+	err = r.commit(nil, []int{id})
 	return
 }
 
 // UpdateFuelType is a method that updates the fuel type of a vehicle in the repository
-func (r *VehicleMap) UpdateFuelType(id int, fuelType string) (err error) {
+func (r *VehicleMap) UpdateFuelType(ctx context.Context, id int, fuelType internal.FuelType) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	if _, ok := r.db[id]; !ok {
 		err = internal.ErrVehicleNotFound
 		return
 	}
+	if !fuelType.Valid() {
+		err = internal.ErrVehicleInvalidFuelType
+		return
+	}
 	vehicle := r.db[id]
 	vehicle.FuelType = fuelType
-	r.db[id] = vehicle
+	err = r.commit([]internal.Vehicle{vehicle}, nil)
 	return
 }
 
 // FindByWeightRange is a method that returns a map of vehicles that match weight range
-func (r *VehicleMap) FindByWeightRange(minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
+func (r *VehicleMap) FindByWeightRange(ctx context.Context, minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	// Search in index
+	v = r.collect(r.ix.weightRange(minWeight, maxWeight))
+
+	if len(v) == 0 {
+		err = internal.ErrVehiclesNotFound
+	}
+
+	return
+}
+
+// FindByBrandAndYearRange is a method that returns a map of vehicles that match brand and year range
+func (r *VehicleMap) FindByBrandAndYearRange(ctx context.Context, brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	// Search in index
+	v = r.collect(r.ix.brandAndYearRange(brand, minYear, maxYear))
+
+	if len(v) == 0 {
+		err = internal.ErrVehiclesNotFound
+	}
+
+	return
+}
+
+// FindAverageMaxSpeedByBrand is a method that returns the average max speed of the vehicles of a brand
+func (r *VehicleMap) FindAverageMaxSpeedByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	var total float64
+	var count int
+
+	// Search in index
+	for _, id := range r.ix.brand(brand) {
+		total += r.db[id].MaxSpeed
+		count++
+	}
+
+	if count == 0 {
+		err = internal.ErrVehiclesNotFound
+		return
+	}
+
+	avg = total / float64(count)
+	return
+}
+
+// UpdateMaxSpeed is a method that updates the max speed of a vehicle in the repository
+func (r *VehicleMap) UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	if _, ok := r.db[id]; !ok {
+		err = internal.ErrVehicleNotFound
+		return
+	}
+	err = ValidateMaxSpeed(maxSpeed)
+	if err != nil {
+		return
+	}
+	vehicle := r.db[id]
+	vehicle.MaxSpeed = maxSpeed
+	err = r.commit([]internal.Vehicle{vehicle}, nil)
+	return
+}
+
+// FindByFuelType is a method that returns a map of vehicles that match fuel type (case insensitive)
+func (r *VehicleMap) FindByFuelType(ctx context.Context, fuelType string) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	v = make(map[int]internal.Vehicle)
 
 	// Search in db
 	for key, value := range r.db {
-		if value.Weight >= minWeight && value.Weight <= maxWeight {
+		if strings.EqualFold(string(value.FuelType), fuelType) {
 			v[key] = value
 		}
 	}
@@ -113,13 +428,125 @@ func (r *VehicleMap) FindByWeightRange(minWeight, maxWeight float64) (v map[int]
 	return
 }
 
-// FindByBrandAndYearRange is a method that returns a map of vehicles that match brand and year range
-func (r *VehicleMap) FindByBrandAndYearRange(brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
+// CountByFuelType is a method that returns the number of vehicles of each fuel type
+// - the counts are read from the index, kept current on every write
+func (r *VehicleMap) CountByFuelType(ctx context.Context) (count map[internal.FuelType]int, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	count = make(map[internal.FuelType]int, len(r.ix.byFuelType))
+	for fuelType, n := range r.ix.byFuelType {
+		count[fuelType] = n
+	}
+	return
+}
+
+// FindByTransmission is a method that returns a map of vehicles that match transmission (case insensitive)
+func (r *VehicleMap) FindByTransmission(ctx context.Context, transmission string) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	v = make(map[int]internal.Vehicle)
+
+	// Search in db
+	for key, value := range r.db {
+		if strings.EqualFold(string(value.Transmission), transmission) {
+			v[key] = value
+		}
+	}
+
+	if len(v) == 0 {
+		err = internal.ErrVehiclesNotFound
+	}
+
+	return
+}
+
+// FindAverageCapacityByBrand is a method that returns the average capacity of the vehicles of a brand
+func (r *VehicleMap) FindAverageCapacityByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	var total int
+	var count int
+
+	// Search in index
+	for _, id := range r.ix.brand(brand) {
+		total += r.db[id].Capacity
+		count++
+	}
+
+	if count == 0 {
+		err = internal.ErrVehiclesNotFound
+		return
+	}
+
+	avg = float64(total) / float64(count)
+	return
+}
+
+// FindByDimensions is a method that returns a map of vehicles that match length and width ranges
+func (r *VehicleMap) FindByDimensions(ctx context.Context, minLength, maxLength, minWidth, maxWidth float64) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	v = make(map[int]internal.Vehicle)
 
 	// Search in db
 	for key, value := range r.db {
-		if value.Brand == brand && value.FabricationYear >= minYear && value.FabricationYear <= maxYear {
+		if value.Length >= minLength && value.Length <= maxLength && value.Width >= minWidth && value.Width <= maxWidth {
+			v[key] = value
+		}
+	}
+
+	if len(v) == 0 {
+		err = internal.ErrVehiclesNotFound
+	}
+
+	return
+}
+
+// Find is a method that returns a map of vehicles that match every filter of the query
+func (r *VehicleMap) Find(ctx context.Context, q internal.VehicleQuery) (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	err = q.Validate()
+	if err != nil {
+		return
+	}
+
+	v = make(map[int]internal.Vehicle)
+
+	// Search in db, a long scan gives up as soon as the context is done
+	i := 0
+	for key, value := range r.db {
+		if i++; i%scanCheckEvery == 0 {
+			if err = ctx.Err(); err != nil {
+				v = nil
+				return
+			}
+		}
+		if q.Match(value) {
 			v[key] = value
 		}
 	}
@@ -130,3 +557,91 @@ func (r *VehicleMap) FindByBrandAndYearRange(brand string, minYear, maxYear int)
 
 	return
 }
+
+// FindPage is a method that returns the page of the vehicles that match every filter of the query, in its order
+func (r *VehicleMap) FindPage(ctx context.Context, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	err = q.Validate()
+	if err != nil {
+		return
+	}
+
+	// Search in db, a long scan gives up as soon as the context is done
+	var v []internal.Vehicle
+	i := 0
+	for _, value := range r.db {
+		if i++; i%scanCheckEvery == 0 {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+		}
+		if q.Match(value) {
+			v = append(v, value)
+		}
+	}
+
+	p = q.Paginate(v)
+	return
+}
+
+// Update is a method that replaces every attribute of a vehicle
+func (r *VehicleMap) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = ValidateVehicle(v)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	if _, ok := r.db[v.Id]; !ok {
+		err = internal.ErrVehicleNotFound
+		return
+	}
+	if r.registrationTaken(v) {
+		err = internal.ErrVehicleRegistrationAlreadyExists
+		return
+	}
+	err = r.commit([]internal.Vehicle{*v}, nil)
+	return
+}
+
+// Patch is a method that applies a change to a vehicle and stores the result if it is valid
+func (r *VehicleMap) Patch(ctx context.Context, id int, patch func(v *internal.Vehicle) (err error)) (v internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	v, ok := r.db[id]
+	if !ok {
+		err = internal.ErrVehicleNotFound
+		return
+	}
+	err = patch(&v)
+	if err != nil {
+		return
+	}
+	v.Id = id
+	err = ValidateVehicle(&v)
+	if err != nil {
+		return
+	}
+	if r.registrationTaken(&v) {
+		err = internal.ErrVehicleRegistrationAlreadyExists
+		return
+	}
+	err = r.commit([]internal.Vehicle{v}, nil)
+	return
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"app/internal"
+	"cmp"
+	"sort"
+	"strings"
+)
+
+// sortedEntry is a struct that represents an entry of a sortedIndex
+type sortedEntry[K cmp.Ordered] struct {
+	// key is the indexed value
+	key K
+	// id is the identifier of the vehicle
+	id int
+}
+
+// sortedIndex is a slice of entries sorted by key and id, used for range searches
+type sortedIndex[K cmp.Ordered] []sortedEntry[K]
+
+// search is a method that returns the position of the first entry not lower than (key, id)
+func (s sortedIndex[K]) search(key K, id int) int {
+	return sort.Search(len(s), func(i int) bool {
+		if c := cmp.Compare(s[i].key, key); c != 0 {
+			return c > 0
+		}
+		return s[i].id >= id
+	})
+}
+
+// insert is a method that adds an entry keeping the slice sorted
+func (s *sortedIndex[K]) insert(key K, id int) {
+	i := s.search(key, id)
+	*s = append(*s, sortedEntry[K]{})
+	copy((*s)[i+1:], (*s)[i:])
+	(*s)[i] = sortedEntry[K]{key: key, id: id}
+}
+
+// remove is a method that deletes an entry
+func (s *sortedIndex[K]) remove(key K, id int) {
+	i := s.search(key, id)
+	if i < len(*s) && (*s)[i].key == key && (*s)[i].id == id {
+		*s = append((*s)[:i], (*s)[i+1:]...)
+	}
+}
+
+// between is a method that returns the ids of the entries with min <= key <= max
+func (s sortedIndex[K]) between(min, max K) (ids []int) {
+	start := sort.Search(len(s), func(i int) bool { return s[i].key >= min })
+	for i := start; i < len(s) && s[i].key <= max; i++ {
+		ids = append(ids, s[i].id)
+	}
+	return
+}
+
+// colorYearKey is a struct that represents the key of the color and year index
+type colorYearKey struct {
+	color string
+	year  int
+}
+
+// vehicleIndex is a struct that represents the secondary indexes of VehicleMap
+// - it is not safe for concurrent use, VehicleMap guards it with its own lock
+type vehicleIndex struct {
+	// byColorYear is a hash index on color and year
+	byColorYear map[colorYearKey]map[int]struct{}
+	// byBrandYear is an index of the vehicles of each brand sorted by year
+	byBrandYear map[string]*sortedIndex[int]
+	// byWeight is an index of the vehicles sorted by weight
+	byWeight sortedIndex[float64]
+	// byRegistration is a unique index on the lowercased registration, every vehicle has one as ValidateVehicle requires it
+	byRegistration map[string]int
+	// byFuelType is the number of vehicles of each fuel type, kept current on every write
+	byFuelType map[internal.FuelType]int
+}
+
+// registrationKey is a function that returns the key of a registration in the registration index
+func registrationKey(registration string) string {
+	return strings.ToLower(registration)
+}
+
+// newVehicleIndex is a function that returns the indexes of the given vehicles
+func newVehicleIndex(db map[int]internal.Vehicle) (ix *vehicleIndex) {
+	ix = &vehicleIndex{
+		byColorYear:    make(map[colorYearKey]map[int]struct{}),
+		byBrandYear:    make(map[string]*sortedIndex[int]),
+		byWeight:       make(sortedIndex[float64], 0, len(db)),
+		byRegistration: make(map[string]int, len(db)),
+		byFuelType:     make(map[internal.FuelType]int),
+	}
+
+	// bulk load: append and sort once instead of inserting one by one
+	for _, v := range db {
+		ix.addColorYear(v)
+		ix.addRegistration(v)
+		ix.byFuelType[v.FuelType]++
+		brand, ok := ix.byBrandYear[v.Brand]
+		if !ok {
+			brand = &sortedIndex[int]{}
+			ix.byBrandYear[v.Brand] = brand
+		}
+		*brand = append(*brand, sortedEntry[int]{key: v.FabricationYear, id: v.Id})
+		ix.byWeight = append(ix.byWeight, sortedEntry[float64]{key: v.Weight, id: v.Id})
+	}
+	for _, brand := range ix.byBrandYear {
+		sortEntries(*brand)
+	}
+	sortEntries(ix.byWeight)
+	return
+}
+
+// sortEntries is a function that sorts the entries of an index by key and id
+func sortEntries[K cmp.Ordered](s sortedIndex[K]) {
+	sort.Slice(s, func(i, j int) bool {
+		if c := cmp.Compare(s[i].key, s[j].key); c != 0 {
+			return c < 0
+		}
+		return s[i].id < s[j].id
+	})
+}
+
+// addColorYear is a method that adds a vehicle to the color and year index
+func (ix *vehicleIndex) addColorYear(v internal.Vehicle) {
+	key := colorYearKey{color: v.Color, year: v.FabricationYear}
+	ids, ok := ix.byColorYear[key]
+	if !ok {
+		ids = make(map[int]struct{})
+		ix.byColorYear[key] = ids
+	}
+	ids[v.Id] = struct{}{}
+}
+
+// addRegistration is a method that adds a vehicle to the registration index
+func (ix *vehicleIndex) addRegistration(v internal.Vehicle) {
+	ix.byRegistration[registrationKey(v.Registration)] = v.Id
+}
+
+// add is a method that adds a vehicle to every index
+func (ix *vehicleIndex) add(v internal.Vehicle) {
+	ix.addColorYear(v)
+	ix.addRegistration(v)
+	ix.byFuelType[v.FuelType]++
+	brand, ok := ix.byBrandYear[v.Brand]
+	if !ok {
+		brand = &sortedIndex[int]{}
+		ix.byBrandYear[v.Brand] = brand
+	}
+	brand.insert(v.FabricationYear, v.Id)
+	ix.byWeight.insert(v.Weight, v.Id)
+}
+
+// remove is a method that removes a vehicle from every index
+func (ix *vehicleIndex) remove(v internal.Vehicle) {
+	key := colorYearKey{color: v.Color, year: v.FabricationYear}
+	if ids, ok := ix.byColorYear[key]; ok {
+		delete(ids, v.Id)
+		if len(ids) == 0 {
+			delete(ix.byColorYear, key)
+		}
+	}
+	if brand, ok := ix.byBrandYear[v.Brand]; ok {
+		brand.remove(v.FabricationYear, v.Id)
+		if len(*brand) == 0 {
+			delete(ix.byBrandYear, v.Brand)
+		}
+	}
+	ix.byWeight.remove(v.Weight, v.Id)
+	if ix.byFuelType[v.FuelType]--; ix.byFuelType[v.FuelType] <= 0 {
+		delete(ix.byFuelType, v.FuelType)
+	}
+	if id, ok := ix.byRegistration[registrationKey(v.Registration)]; ok && id == v.Id {
+		delete(ix.byRegistration, registrationKey(v.Registration))
+	}
+}
+
+// colorAndYear is a method that returns the ids of the vehicles that match color and year
+func (ix *vehicleIndex) colorAndYear(color string, year int) (ids []int) {
+	for id := range ix.byColorYear[colorYearKey{color: color, year: year}] {
+		ids = append(ids, id)
+	}
+	return
+}
+
+// brand is a method that returns the ids of the vehicles of a brand
+func (ix *vehicleIndex) brand(brand string) (ids []int) {
+	years, ok := ix.byBrandYear[brand]
+	if !ok {
+		return
+	}
+	ids = make([]int, len(*years))
+	for i, e := range *years {
+		ids[i] = e.id
+	}
+	return
+}
+
+// brandAndYearRange is a method that returns the ids of the vehicles that match brand and year range
+func (ix *vehicleIndex) brandAndYearRange(brand string, minYear, maxYear int) (ids []int) {
+	years, ok := ix.byBrandYear[brand]
+	if !ok {
+		return
+	}
+	ids = years.between(minYear, maxYear)
+	return
+}
+
+// weightRange is a method that returns the ids of the vehicles that match weight range
+func (ix *vehicleIndex) weightRange(minWeight, maxWeight float64) (ids []int) {
+	ids = ix.byWeight.between(minWeight, maxWeight)
+	return
+}
+
+// registration is a method that returns the id of the vehicle with a registration (case insensitive)
+func (ix *vehicleIndex) registration(registration string) (id int, ok bool) {
+	id, ok = ix.byRegistration[registrationKey(registration)]
+	return
+}
@@ -0,0 +1,262 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestVehicleMap_Concurrency hammers every method of VehicleMap in parallel, it is meant to be run with -race
+func TestVehicleMap_Concurrency(t *testing.T) {
+	// ARRANGE
+	// - repository with some initial vehicles
+	db := make(map[int]internal.Vehicle)
+	for i := 1; i <= 100; i++ {
+		db[i] = *internal.NewVehicle(i, "Toyota", "Corolla", fmt.Sprintf("ABC-%04d", i), "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77)
+	}
+	rp := repository.NewVehicleMap(db)
+
+	// - operations, each one receives the index of the worker
+	operations := []func(i int){
+		func(i int) { _, _ = rp.FindAll(context.Background()) },
+		func(i int) { _, _ = rp.FindById(context.Background(), i%100+1) },
+		func(i int) { _, _ = rp.FindByRegistration(context.Background(), fmt.Sprintf("abc-%04d", i%100+1)) },
+		func(i int) {
+			_ = rp.Create(context.Background(), internal.NewVehicle(1000+i, "Ford", "Fiesta", fmt.Sprintf("DEF-%04d", i), "Red", 2019, 4, 170.0, "diesel", "manual", 1100.0, 1.4, 4.0, 1.7))
+		},
+		func(i int) {
+			_ = rp.BatchCreate(context.Background(), []*internal.Vehicle{
+				internal.NewVehicle(2000+i*2, "Ford", "Focus", fmt.Sprintf("GHI-%04d", i*2), "Black", 2018, 5, 190.0, "diesel", "manual", 1200.0, 1.4, 4.3, 1.8),
+				internal.NewVehicle(2000+i*2+1, "Ford", "Focus", fmt.Sprintf("GHI-%04d", i*2+1), "Black", 2018, 5, 190.0, "diesel", "manual", 1200.0, 1.4, 4.3, 1.8),
+			})
+		},
+		func(i int) { _, _ = rp.FindByColorAndYear(context.Background(), "Blue", 2020) },
+		func(i int) { _ = rp.Delete(context.Background(), i%100+1) },
+		func(i int) { _ = rp.UpdateFuelType(context.Background(), i%100+1, "diesel") },
+		func(i int) { _, _ = rp.FindByWeightRange(context.Background(), 1000, 1500) },
+		func(i int) { _, _ = rp.FindByBrandAndYearRange(context.Background(), "Toyota", 2000, 2025) },
+		func(i int) { _, _ = rp.FindAverageMaxSpeedByBrand(context.Background(), "Toyota") },
+		func(i int) { _ = rp.UpdateMaxSpeed(context.Background(), i%100+1, 200.0) },
+		func(i int) { _, _ = rp.FindByFuelType(context.Background(), "gasoline") },
+		func(i int) { _, _ = rp.FindByTransmission(context.Background(), "automatic") },
+		func(i int) { _, _ = rp.FindAverageCapacityByBrand(context.Background(), "Toyota") },
+		func(i int) { _, _ = rp.FindByDimensions(context.Background(), 4, 5, 1, 2) },
+		func(i int) {
+			_, _ = rp.Find(context.Background(), internal.VehicleQuery{Filters: []internal.VehicleFilter{{Field: "brand", Operator: internal.OpEq, Values: []any{"Toyota"}}}})
+		},
+		func(i int) {
+			_, _ = rp.FindPage(context.Background(), internal.VehicleQuery{Sort: []internal.VehicleSort{{Field: "max_speed", Desc: true}}, Limit: 10, Offset: i % 20})
+		},
+		func(i int) {
+			_ = rp.Update(context.Background(), internal.NewVehicle(i%100+1, "Toyota", "Yaris", fmt.Sprintf("ABC-%04d", i%100+1), "White", 2021, 5, 170.0, "gasoline", "manual", 1000.0, 1.5, 3.9, 1.7))
+		},
+		func(i int) {
+			_, _ = rp.Patch(context.Background(), i%100+1, func(v *internal.Vehicle) error {
+				v.Color = "Green"
+				return nil
+			})
+		},
+	}
+
+	// ACT
+	const workers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		for _, op := range operations {
+			wg.Add(1)
+			go func(i int, op func(i int)) {
+				defer wg.Done()
+				op(i)
+			}(i, op)
+		}
+	}
+	wg.Wait()
+
+	// ASSERT
+	// - every create and batch create must have been stored
+	v, err := rp.FindAll(context.Background())
+	require.NoError(t, err)
+	for i := 0; i < workers; i++ {
+		require.Contains(t, v, 1000+i)
+		require.Contains(t, v, 2000+i*2)
+		require.Contains(t, v, 2000+i*2+1)
+	}
+	// - every deleted vehicle must be gone
+	for i := 0; i < workers; i++ {
+		require.NotContains(t, v, i%100+1)
+	}
+}
+
+// TestVehicleMap_ConcurrentIdAllocation creates vehicles without id in parallel, it is meant to be run with -race
+func TestVehicleMap_ConcurrentIdAllocation(t *testing.T) {
+	// ARRANGE
+	// - repository with some initial vehicles
+	db := make(map[int]internal.Vehicle)
+	for i := 1; i <= 100; i++ {
+		db[i] = *internal.NewVehicle(i, "Toyota", "Corolla", fmt.Sprintf("ABC-%04d", i), "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 4.62, 1.77)
+	}
+	rp := repository.NewVehicleMap(db)
+
+	// ACT
+	// - the goroutines only record their result, the assertions run on the test goroutine
+	const workers = 100
+	ids := make([]int, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vehicle := internal.NewVehicle(0, "Ford", "Fiesta", fmt.Sprintf("DEF-%04d", i), "Red", 2019, 4, 170.0, "diesel", "manual", 1100.0, 1.4, 4.0, 1.7)
+			errs[i] = rp.Create(context.Background(), vehicle)
+			ids[i] = vehicle.Id
+		}(i)
+	}
+	wg.Wait()
+
+	// ASSERT
+	for i, err := range errs {
+		require.NoError(t, err, "worker %d", i)
+	}
+	// - every vehicle must have received its own id, right after the loaded ones
+	slices.Sort(ids)
+	for i, id := range ids {
+		require.Equal(t, 101+i, id)
+	}
+}
+
+// scanByColorAndYear, scanByBrandAndYearRange and scanByWeightRange are full scans used as reference for the indexes
+func scanByColorAndYear(db map[int]internal.Vehicle, color string, year int) map[int]internal.Vehicle {
+	v := make(map[int]internal.Vehicle)
+	for key, value := range db {
+		if value.Color == color && value.FabricationYear == year {
+			v[key] = value
+		}
+	}
+	return v
+}
+
+func scanByBrandAndYearRange(db map[int]internal.Vehicle, brand string, minYear, maxYear int) map[int]internal.Vehicle {
+	v := make(map[int]internal.Vehicle)
+	for key, value := range db {
+		if value.Brand == brand && value.FabricationYear >= minYear && value.FabricationYear <= maxYear {
+			v[key] = value
+		}
+	}
+	return v
+}
+
+func scanByWeightRange(db map[int]internal.Vehicle, minWeight, maxWeight float64) map[int]internal.Vehicle {
+	v := make(map[int]internal.Vehicle)
+	for key, value := range db {
+		if value.Weight >= minWeight && value.Weight <= maxWeight {
+			v[key] = value
+		}
+	}
+	return v
+}
+
+// generateVehicles is a function that returns n vehicles spread over a few brands, colors, years and weights
+func generateVehicles(n int) map[int]internal.Vehicle {
+	brands := []string{"Toyota", "Ford", "Chevrolet", "GMC", "Kia", "Mazda", "Acura", "Lexus"}
+	colors := []string{"Blue", "Red", "Green", "Black", "White", "Orange", "Teal", "Puce", "Mauv", "Khaki"}
+	db := make(map[int]internal.Vehicle, n)
+	for i := 1; i <= n; i++ {
+		db[i] = *internal.NewVehicle(i, brands[i%len(brands)], "Model", fmt.Sprintf("ABC-%06d", i), colors[(i/7)%len(colors)], 1970+(i*13)%55, 1+i%7, float64(80+i%200), "gasoline", "manual", float64(i%3000)/10, 1.5, 4.5, 1.8)
+	}
+	return db
+}
+
+func TestVehicleMap_Indexes(t *testing.T) {
+	t.Run("should match a full scan after mutations", func(t *testing.T) {
+		// ARRANGE
+		rp := repository.NewVehicleMap(generateVehicles(2000))
+
+		// ACT
+		// - mutate vehicles through every path that touches the indexes
+		for i := 1; i <= 2000; i += 3 {
+			require.NoError(t, rp.Delete(context.Background(), i))
+		}
+		for i := 2; i <= 2000; i += 3 {
+			require.NoError(t, rp.UpdateMaxSpeed(context.Background(), i, 120))
+		}
+		require.NoError(t, rp.Create(context.Background(), internal.NewVehicle(5000, "Toyota", "Corolla", "XYZ-5000", "Blue", 2000, 5, 180.0, "gasoline", "automatic", 150.5, 1.45, 4.62, 1.77)))
+		require.NoError(t, rp.BatchCreate(context.Background(), []*internal.Vehicle{
+			internal.NewVehicle(5001, "Ford", "Fiesta", "XYZ-5001", "Blue", 2000, 5, 170.0, "diesel", "manual", 150.5, 1.4, 4.0, 1.7),
+		}))
+
+		// ASSERT
+		db, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		for _, color := range []string{"Blue", "Red", "Khaki"} {
+			for year := 1970; year < 2025; year += 6 {
+				v, _ := rp.FindByColorAndYear(context.Background(), color, year)
+				require.Equal(t, scanByColorAndYear(db, color, year), v)
+			}
+		}
+		for _, brand := range []string{"Toyota", "Ford", "Unknown"} {
+			v, _ := rp.FindByBrandAndYearRange(context.Background(), brand, 1990, 2005)
+			require.Equal(t, scanByBrandAndYearRange(db, brand, 1990, 2005), v)
+		}
+		v, _ := rp.FindByWeightRange(context.Background(), 100, 150.5)
+		require.Equal(t, scanByWeightRange(db, 100, 150.5), v)
+	})
+}
+
+// The benchmarks compare the indexed queries of VehicleMap with a full scan over the same fleet
+// - go test -run ^$ -bench VehicleMap ./internal/repository/
+const benchmarkFleetSize = 200000
+
+func BenchmarkVehicleMap_FindByColorAndYear(b *testing.B) {
+	db := generateVehicles(benchmarkFleetSize)
+	rp := repository.NewVehicleMap(db)
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByColorAndYear(context.Background(), "Blue", 2000)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = scanByColorAndYear(db, "Blue", 2000)
+		}
+	})
+}
+
+func BenchmarkVehicleMap_FindByBrandAndYearRange(b *testing.B) {
+	db := generateVehicles(benchmarkFleetSize)
+	rp := repository.NewVehicleMap(db)
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByBrandAndYearRange(context.Background(), "Toyota", 2000, 2002)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = scanByBrandAndYearRange(db, "Toyota", 2000, 2002)
+		}
+	})
+}
+
+func BenchmarkVehicleMap_FindByWeightRange(b *testing.B) {
+	db := generateVehicles(benchmarkFleetSize)
+	rp := repository.NewVehicleMap(db)
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = rp.FindByWeightRange(context.Background(), 100, 101)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = scanByWeightRange(db, 100, 101)
+		}
+	})
+}
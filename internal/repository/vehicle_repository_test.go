@@ -0,0 +1,82 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"app/internal/repository"
+	"app/internal/repository/repotest"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// vehicleRepositoryFactories is the list of repositories verified against the conformance suite
+var vehicleRepositoryFactories = map[string]repotest.Factory{
+	"VehicleMap": func(t *testing.T, db map[int]internal.Vehicle) internal.VehicleRepository {
+		return repository.NewVehicleMap(db)
+	},
+	"VehicleFile": func(t *testing.T, db map[int]internal.Vehicle) internal.VehicleRepository {
+		return repository.NewVehicleFile(db, loader.NewVehicleJSONFile(filepath.Join(t.TempDir(), "vehicles.json")))
+	},
+	"VehicleLog": func(t *testing.T, db map[int]internal.Vehicle) internal.VehicleRepository {
+		dir := t.TempDir()
+		rp, err := repository.NewVehicleLog(db, loader.NewVehicleJSONFile(filepath.Join(dir, "vehicles.json")), filepath.Join(dir, "vehicles.json.log"), 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { rp.Close() })
+		return rp
+	},
+	"VehicleSQL": func(t *testing.T, db map[int]internal.Vehicle) internal.VehicleRepository {
+		conn, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vehicles.db"))
+		require.NoError(t, err)
+		conn.SetMaxOpenConns(1)
+		t.Cleanup(func() { conn.Close() })
+
+		rp := repository.NewVehicleSQL(conn)
+		require.NoError(t, rp.Migrate())
+		for _, v := range db {
+			require.NoError(t, rp.Create(context.Background(), &v))
+		}
+		return rp
+	},
+	"VehicleInstrumented": func(t *testing.T, db map[int]internal.Vehicle) internal.VehicleRepository {
+		return repository.NewVehicleInstrumented(repository.NewVehicleMap(db))
+	},
+}
+
+func TestVehicleRepository(t *testing.T) {
+	for name, factory := range vehicleRepositoryFactories {
+		t.Run(name, func(t *testing.T) {
+			repotest.Run(t, factory)
+		})
+	}
+}
+
+func TestVehicleRepository_SeedFile(t *testing.T) {
+	for name, factory := range vehicleRepositoryFactories {
+		t.Run(name+" should patch and replace a vehicle of the seed file", func(t *testing.T) {
+			// - the seed file shipped with the repository, its vehicles have no length
+			db, _, err := loader.NewVehicleJSONFile("../../docs/db/vehicles_100.json").LoadKeepFirst()
+			require.NoError(t, err)
+			require.NoError(t, repository.ValidateVehicles(db))
+			rp := factory(t, db)
+
+			v, err := rp.Patch(context.Background(), 1, func(v *internal.Vehicle) error {
+				v.Color = "Grey"
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "Grey", v.Color)
+			require.Zero(t, v.Length)
+
+			v.MaxSpeed = 150.0
+			require.NoError(t, rp.Update(context.Background(), &v))
+			got, err := rp.FindById(context.Background(), 1)
+			require.NoError(t, err)
+			require.Equal(t, v, got)
+		})
+	}
+}
@@ -0,0 +1,835 @@
+package repository
+
+import (
+	"app/internal"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// vehicleSQLMigrations is the list of migrations of the vehicles schema, applied in order
+// - migrations are never edited once released, changes are appended as new migrations
+var vehicleSQLMigrations = []string{
+	// 1: vehicles table
+	`CREATE TABLE vehicles (
+		id INTEGER PRIMARY KEY,
+		brand TEXT NOT NULL,
+		model TEXT NOT NULL,
+		registration TEXT NOT NULL,
+		color TEXT NOT NULL,
+		year INTEGER NOT NULL,
+		passengers INTEGER NOT NULL,
+		max_speed REAL NOT NULL,
+		fuel_type TEXT NOT NULL,
+		transmission TEXT NOT NULL,
+		weight REAL NOT NULL,
+		height REAL NOT NULL,
+		length REAL NOT NULL,
+		width REAL NOT NULL
+	)`,
+	// 2: indexes for the search queries
+	`CREATE INDEX idx_vehicles_brand_year ON vehicles (brand, year);
+	CREATE INDEX idx_vehicles_color_year ON vehicles (color, year);
+	CREATE INDEX idx_vehicles_year ON vehicles (year);
+	CREATE INDEX idx_vehicles_weight ON vehicles (weight)`,
+	// 3: unique registrations, empty ones are not indexed
+	`CREATE UNIQUE INDEX idx_vehicles_registration ON vehicles (registration COLLATE NOCASE) WHERE registration <> ''`,
+	// 4: highest id stored so far, omitted ids are allocated after it so that they are never reused
+	`CREATE TABLE vehicle_ids (last_id INTEGER NOT NULL);
+	INSERT INTO vehicle_ids (last_id) SELECT COALESCE(MAX(id), 0) FROM vehicles`,
+	// 5: registrations are required, so the unique index covers every vehicle
+	`DROP INDEX idx_vehicles_registration;
+	CREATE UNIQUE INDEX idx_vehicles_registration ON vehicles (registration COLLATE NOCASE)`,
+}
+
+// vehicleSQLMigrationChecks is the list of checks run before a migration, by version
+// - a check fails the migration with an error that tells how to fix the data, instead of the bare constraint error
+var vehicleSQLMigrationChecks = map[int]func(tx *sql.Tx) error{
+	3: checkRegistrationConflicts,
+	5: checkMissingRegistrations,
+}
+
+// ErrVehicleRegistrationMissing is an error that represents that stored vehicles have no registration, which ValidateVehicle requires
+var ErrVehicleRegistrationMissing = errors.New("vehicle registration missing")
+
+// checkMissingRegistrations is a function that fails with ErrVehicleRegistrationMissing, naming the vehicles,
+// if some vehicles have an empty registration, which the index of every registration can not hold more than once
+func checkMissingRegistrations(tx *sql.Tx) (err error) {
+	rows, err := tx.Query("SELECT id FROM vehicles WHERE registration = '' ORDER BY id")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	if err != nil {
+		return
+	}
+
+	if len(ids) > 0 {
+		err = fmt.Errorf("%w: ids %v", ErrVehicleRegistrationMissing, ids)
+	}
+	return
+}
+
+// checkRegistrationConflicts is a function that fails with *internal.VehicleRegistrationConflictError
+// if several vehicles share a registration (case insensitive), so that the unique index can not be created
+func checkRegistrationConflicts(tx *sql.Tx) (err error) {
+	rows, err := tx.Query(`SELECT id, registration FROM vehicles WHERE registration <> '' AND registration COLLATE NOCASE IN (
+		SELECT registration FROM vehicles WHERE registration <> '' GROUP BY registration COLLATE NOCASE HAVING COUNT(*) > 1
+	)`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	v := make(map[int]internal.Vehicle)
+	for rows.Next() {
+		var vehicle internal.Vehicle
+		err = rows.Scan(&vehicle.Id, &vehicle.Registration)
+		if err != nil {
+			return
+		}
+		v[vehicle.Id] = vehicle
+	}
+	err = rows.Err()
+	if err != nil {
+		return
+	}
+
+	if c := internal.FindRegistrationConflicts(v); len(c) > 0 {
+		err = &internal.VehicleRegistrationConflictError{Conflicts: c}
+	}
+	return
+}
+
+// vehicleSQLColumns is the list of columns selected to scan a vehicle
+const vehicleSQLColumns = "id, brand, model, registration, color, year, passengers, max_speed, fuel_type, transmission, weight, height, length, width"
+
+// NewVehicleSQL is a function that returns a new instance of VehicleSQL
+func NewVehicleSQL(db *sql.DB) *VehicleSQL {
+	return &VehicleSQL{db: db}
+}
+
+// VehicleSQL is a struct that represents a vehicle repository backed by a SQL database (SQLite dialect)
+type VehicleSQL struct {
+	// db is the database connection pool
+	db *sql.DB
+}
+
+// Migrate is a method that applies the pending migrations of the vehicles schema
+func (r *VehicleSQL) Migrate() (err error) {
+	_, err = r.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)")
+	if err != nil {
+		return
+	}
+
+	// current version
+	var version int
+	err = r.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return
+	}
+
+	// apply pending migrations, each one in its own transaction
+	for i := version; i < len(vehicleSQLMigrations); i++ {
+		var tx *sql.Tx
+		tx, err = r.db.Begin()
+		if err != nil {
+			return
+		}
+		if check, ok := vehicleSQLMigrationChecks[i+1]; ok {
+			err = check(tx)
+			if err != nil {
+				tx.Rollback()
+				err = fmt.Errorf("migration %d: %w", i+1, err)
+				return
+			}
+		}
+		_, err = tx.Exec(vehicleSQLMigrations[i])
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		_, err = tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", i+1)
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// scanVehicle is a function that scans the current row of a query selecting vehicleSQLColumns
+func scanVehicle(rows *sql.Rows) (v internal.Vehicle, err error) {
+	err = rows.Scan(&v.Id, &v.Brand, &v.Model, &v.Registration, &v.Color, &v.FabricationYear, &v.Capacity, &v.MaxSpeed, &v.FuelType, &v.Transmission, &v.Weight, &v.Height, &v.Length, &v.Width)
+	return
+}
+
+// scanVehicles is a function that scans the rows of a query selecting vehicleSQLColumns
+func scanVehicles(rows *sql.Rows) (v map[int]internal.Vehicle, err error) {
+	defer rows.Close()
+
+	v = make(map[int]internal.Vehicle)
+	for rows.Next() {
+		var vh internal.Vehicle
+		vh, err = scanVehicle(rows)
+		if err != nil {
+			return
+		}
+		v[vh.Id] = vh
+	}
+	err = rows.Err()
+	return
+}
+
+// find is a method that returns a map of the vehicles matching the where clause
+// - the result is never empty, internal.ErrVehiclesNotFound is returned instead
+func (r *VehicleSQL) find(ctx context.Context, where string, args ...any) (v map[int]internal.Vehicle, err error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+vehicleSQLColumns+" FROM vehicles WHERE "+where, args...)
+	if err != nil {
+		return
+	}
+	v, err = scanVehicles(rows)
+	if err != nil {
+		return
+	}
+
+	if len(v) == 0 {
+		err = internal.ErrVehiclesNotFound
+	}
+	return
+}
+
+// average is a method that returns the average of a column for the vehicles of a brand
+func (r *VehicleSQL) average(ctx context.Context, column string, brand string) (avg float64, err error) {
+	var count int
+	var total sql.NullFloat64
+	err = r.db.QueryRowContext(ctx, "SELECT COUNT(*), AVG("+column+") FROM vehicles WHERE brand = ?", brand).Scan(&count, &total)
+	if err != nil {
+		return
+	}
+
+	if count == 0 {
+		err = internal.ErrVehiclesNotFound
+		return
+	}
+
+	avg = total.Float64
+	return
+}
+
+// FindAll is a method that returns a map of all vehicles
+func (r *VehicleSQL) FindAll(ctx context.Context) (v map[int]internal.Vehicle, err error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+vehicleSQLColumns+" FROM vehicles")
+	if err != nil {
+		return
+	}
+	v, err = scanVehicles(rows)
+	return
+}
+
+// execer is an interface that represents a *sql.DB or a *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// exists is a function that returns whether a vehicle exists by id, using ex
+func exists(ctx context.Context, ex execer, id int) (ok bool, err error) {
+	err = ex.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM vehicles WHERE id = ?)", id).Scan(&ok)
+	return
+}
+
+// registrationTaken is a function that returns whether another vehicle has the registration of v, using ex
+func registrationTaken(ctx context.Context, ex execer, v *internal.Vehicle) (taken bool, err error) {
+	err = ex.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM vehicles WHERE registration = ? COLLATE NOCASE AND id <> ?)", v.Registration, v.Id).Scan(&taken)
+	return
+}
+
+// allocateId is a function that allocates the next id of a vehicle, using ex
+func allocateId(ctx context.Context, ex execer) (id int, err error) {
+	err = ex.QueryRowContext(ctx, "UPDATE vehicle_ids SET last_id = last_id + 1 RETURNING last_id").Scan(&id)
+	return
+}
+
+// insert is a function that inserts a vehicle using ex
+// - it reports internal.ErrVehicleAlreadyExists if the id is taken and internal.ErrVehicleRegistrationAlreadyExists if the registration is
+func insert(ctx context.Context, ex execer, v *internal.Vehicle) (err error) {
+	ok, err := exists(ctx, ex, v.Id)
+	if err != nil {
+		return
+	}
+	if ok {
+		err = internal.ErrVehicleAlreadyExists
+		return
+	}
+	taken, err := registrationTaken(ctx, ex, v)
+	if err != nil {
+		return
+	}
+	if taken {
+		err = internal.ErrVehicleRegistrationAlreadyExists
+		return
+	}
+
+	_, err = ex.ExecContext(ctx,
+		"INSERT INTO vehicles ("+vehicleSQLColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		v.Id, v.Brand, v.Model, v.Registration, v.Color, v.FabricationYear, v.Capacity, v.MaxSpeed, v.FuelType, v.Transmission, v.Weight, v.Height, v.Length, v.Width,
+	)
+	if err != nil {
+		return
+	}
+	_, err = ex.ExecContext(ctx, "UPDATE vehicle_ids SET last_id = MAX(last_id, ?)", v.Id)
+	return
+}
+
+// Seed is a method that adds the vehicles of a snapshot, keeping their ids
+// - every vehicle is checked with ValidateVehicle first, so that the stored vehicles follow the same rules as the writes
+// - nothing is added if any vehicle is not valid
+func (r *VehicleSQL) Seed(v map[int]internal.Vehicle) (err error) {
+	err = ValidateVehicles(v)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	for _, vehicle := range v {
+		vehicle := vehicle
+		err = insert(ctx, tx, &vehicle)
+		if err != nil {
+			return
+		}
+	}
+	err = tx.Commit()
+	return
+}
+
+// findOne is a method that returns the vehicle matching the where clause
+// - internal.ErrVehicleNotFound is returned if there is none
+func (r *VehicleSQL) findOne(ctx context.Context, where string, args ...any) (v internal.Vehicle, err error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+vehicleSQLColumns+" FROM vehicles WHERE "+where+" LIMIT 1", args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		err = rows.Err()
+		if err == nil {
+			err = internal.ErrVehicleNotFound
+		}
+		return
+	}
+	v, err = scanVehicle(rows)
+	return
+}
+
+// FindById is a method that returns a vehicle by id
+func (r *VehicleSQL) FindById(ctx context.Context, id int) (v internal.Vehicle, err error) {
+	v, err = r.findOne(ctx, "id = ?", id)
+	return
+}
+
+// FindByRegistration is a method that returns a vehicle by registration (case insensitive)
+func (r *VehicleSQL) FindByRegistration(ctx context.Context, registration string) (v internal.Vehicle, err error) {
+	v, err = r.findOne(ctx, "registration = ? COLLATE NOCASE", registration)
+	return
+}
+
+// Create is a method that adds a vehicle to the repository
+// - if the id is omitted (0) the next one is allocated and set on v
+func (r *VehicleSQL) Create(ctx context.Context, v *internal.Vehicle) (err error) {
+	// allocate, check and insert in the same transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	vehicle := *v
+	if vehicle.Id == 0 {
+		vehicle.Id, err = allocateId(ctx, tx)
+		if err != nil {
+			return
+		}
+	}
+	err = ValidateVehicle(&vehicle)
+	if err != nil {
+		return
+	}
+	err = insert(ctx, tx, &vehicle)
+	if err != nil {
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		return
+	}
+	v.Id = vehicle.Id
+	return
+}
+
+// BatchCreate is a method that adds a list of vehicles to the repository
+// - either all vehicles are created or none of them, in which case a *internal.VehicleBatchError is returned
+// - the omitted ids (0) are allocated after the highest id of the repository and of the batch, and set on v
+func (r *VehicleSQL) BatchCreate(ctx context.Context, v []*internal.Vehicle) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// allocate the omitted ids
+	lastId := 0
+	for _, vehicle := range v {
+		lastId = max(lastId, vehicle.Id)
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE vehicle_ids SET last_id = MAX(last_id, ?)", lastId)
+	if err != nil {
+		return
+	}
+	vehicles := make([]internal.Vehicle, len(v))
+	for i, vehicle := range v {
+		vehicles[i] = *vehicle
+		if vehicles[i].Id == 0 {
+			vehicles[i].Id, err = allocateId(ctx, tx)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// validate every vehicle before creating any
+	batchErr := &internal.VehicleBatchError{}
+	seen := make(map[int]bool)
+	seenRegistrations := make(map[string]bool)
+	for i := range vehicles {
+		vehicle := &vehicles[i]
+		itemErr := ValidateVehicle(vehicle)
+		if itemErr == nil {
+			var ok, taken bool
+			ok, err = exists(ctx, tx, vehicle.Id)
+			if err != nil {
+				return
+			}
+			taken, err = registrationTaken(ctx, tx, vehicle)
+			if err != nil {
+				return
+			}
+			if ok {
+				itemErr = internal.ErrVehicleAlreadyExists
+			} else if seen[vehicle.Id] {
+				itemErr = internal.ErrVehicleDuplicatedInBatch
+			} else if taken {
+				itemErr = internal.ErrVehicleRegistrationAlreadyExists
+			} else if seenRegistrations[registrationKey(vehicle.Registration)] {
+				itemErr = internal.ErrVehicleRegistrationDuplicatedInBatch
+			}
+		}
+		if itemErr != nil {
+			batchErr.Items = append(batchErr.Items, internal.VehicleBatchItemError{Index: i, Id: v[i].Id, Err: itemErr})
+			continue
+		}
+		seen[vehicle.Id] = true
+		seenRegistrations[registrationKey(vehicle.Registration)] = true
+	}
+	if len(batchErr.Items) > 0 {
+		err = batchErr
+		return
+	}
+
+	// create vehicles
+	for i := range vehicles {
+		err = insert(ctx, tx, &vehicles[i])
+		if err != nil {
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return
+	}
+	for i, vehicle := range vehicles {
+		v[i].Id = vehicle.Id
+	}
+	return
+}
+
+// FindByColorAndYear is a method that returns a map of vehicles that match color and year
+func (r *VehicleSQL) FindByColorAndYear(ctx context.Context, color string, year int) (v map[int]internal.Vehicle, err error) {
+	v, err = r.find(ctx, "color = ? AND year = ?", color, year)
+	return
+}
+
+// Delete is a method that deletes a vehicle from the repository
+func (r *VehicleSQL) Delete(ctx context.Context, id int) (err error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM vehicles WHERE id = ?", id)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	if rowsAffected == 0 {
+		err = internal.ErrVehicleNotFound
+	}
+	return
+}
+
+// UpdateFuelType is a method that updates the fuel type of a vehicle in the repository
+// - the vehicle is only looked up on its own when the fuel type is invalid, as not found takes precedence
+func (r *VehicleSQL) UpdateFuelType(ctx context.Context, id int, fuelType internal.FuelType) (err error) {
+	if !fuelType.Valid() {
+		err = r.notFoundOr(ctx, id, internal.ErrVehicleInvalidFuelType)
+		return
+	}
+
+	err = r.updateColumn(ctx, id, "fuel_type", fuelType)
+	return
+}
+
+// notFoundOr is a method that returns internal.ErrVehicleNotFound if the vehicle does not exist, or vErr otherwise
+func (r *VehicleSQL) notFoundOr(ctx context.Context, id int, vErr error) (err error) {
+	ok, err := exists(ctx, r.db, id)
+	if err != nil {
+		return
+	}
+	err = vErr
+	if !ok {
+		err = internal.ErrVehicleNotFound
+	}
+	return
+}
+
+// updateColumn is a method that sets a column of a vehicle with a single statement
+// - no affected row means that the vehicle does not exist, even if it was deleted right before
+func (r *VehicleSQL) updateColumn(ctx context.Context, id int, column string, value any) (err error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE vehicles SET "+column+" = ? WHERE id = ?", value, id)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	if rowsAffected == 0 {
+		err = internal.ErrVehicleNotFound
+	}
+	return
+}
+
+// FindByWeightRange is a method that returns a map of vehicles that match weight range
+func (r *VehicleSQL) FindByWeightRange(ctx context.Context, minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
+	v, err = r.find(ctx, "weight BETWEEN ? AND ?", minWeight, maxWeight)
+	return
+}
+
+// FindByBrandAndYearRange is a method that returns a map of vehicles that match brand and year range
+func (r *VehicleSQL) FindByBrandAndYearRange(ctx context.Context, brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
+	v, err = r.find(ctx, "brand = ? AND year BETWEEN ? AND ?", brand, minYear, maxYear)
+	return
+}
+
+// FindAverageMaxSpeedByBrand is a method that returns the average max speed of the vehicles of a brand
+func (r *VehicleSQL) FindAverageMaxSpeedByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	avg, err = r.average(ctx, "max_speed", brand)
+	return
+}
+
+// UpdateMaxSpeed is a method that updates the max speed of a vehicle in the repository
+// - the vehicle is only looked up on its own when the max speed is invalid, as not found takes precedence
+func (r *VehicleSQL) UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error) {
+	if vErr := ValidateMaxSpeed(maxSpeed); vErr != nil {
+		err = r.notFoundOr(ctx, id, vErr)
+		return
+	}
+
+	err = r.updateColumn(ctx, id, "max_speed", maxSpeed)
+	return
+}
+
+// FindByFuelType is a method that returns a map of vehicles that match fuel type (case insensitive)
+func (r *VehicleSQL) FindByFuelType(ctx context.Context, fuelType string) (v map[int]internal.Vehicle, err error) {
+	v, err = r.find(ctx, "fuel_type = ? COLLATE NOCASE", fuelType)
+	return
+}
+
+// CountByFuelType is a method that returns the number of vehicles of each fuel type
+func (r *VehicleSQL) CountByFuelType(ctx context.Context) (count map[internal.FuelType]int, err error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT fuel_type, COUNT(*) FROM vehicles GROUP BY fuel_type")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	count = make(map[internal.FuelType]int)
+	for rows.Next() {
+		var fuelType internal.FuelType
+		var n int
+		if err = rows.Scan(&fuelType, &n); err != nil {
+			return
+		}
+		count[fuelType] = n
+	}
+	err = rows.Err()
+	return
+}
+
+// FindByTransmission is a method that returns a map of vehicles that match transmission (case insensitive)
+func (r *VehicleSQL) FindByTransmission(ctx context.Context, transmission string) (v map[int]internal.Vehicle, err error) {
+	v, err = r.find(ctx, "transmission = ? COLLATE NOCASE", transmission)
+	return
+}
+
+// FindAverageCapacityByBrand is a method that returns the average capacity of the vehicles of a brand
+func (r *VehicleSQL) FindAverageCapacityByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	avg, err = r.average(ctx, "passengers", brand)
+	return
+}
+
+// FindByDimensions is a method that returns a map of vehicles that match length and width ranges
+func (r *VehicleSQL) FindByDimensions(ctx context.Context, minLength, maxLength, minWidth, maxWidth float64) (v map[int]internal.Vehicle, err error) {
+	v, err = r.find(ctx, "length BETWEEN ? AND ? AND width BETWEEN ? AND ?", minLength, maxLength, minWidth, maxWidth)
+	return
+}
+
+// sqlOperators is the SQL operator of each comparison operator of internal.VehicleQuery
+var sqlOperators = map[string]string{
+	internal.OpEq:  "=",
+	internal.OpGt:  ">",
+	internal.OpGte: ">=",
+	internal.OpLt:  "<",
+	internal.OpLte: "<=",
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// where is a function that returns the where clause and its arguments for the filters of a query
+// - columns are named after the JSON fields, the query is validated first so that only fields of internal.VehicleFields reach the SQL
+func where(q internal.VehicleQuery) (clause string, args []any, err error) {
+	err = q.Validate()
+	if err != nil {
+		return
+	}
+
+	conditions := []string{"1 = 1"}
+	for _, f := range q.Filters {
+		column := f.Field
+		if internal.VehicleFields[f.Field].Kind == internal.FieldKindText {
+			column += " COLLATE NOCASE"
+		}
+
+		switch f.Operator {
+		case internal.OpIn:
+			conditions = append(conditions, column+" IN (?"+strings.Repeat(", ?", len(f.Values)-1)+")")
+			args = append(args, f.Values...)
+		case internal.OpContains:
+			conditions = append(conditions, f.Field+` LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+likeEscaper.Replace(f.Values[0].(string))+"%")
+		default:
+			conditions = append(conditions, column+" "+sqlOperators[f.Operator]+" ?")
+			args = append(args, f.Values[0])
+		}
+	}
+
+	clause = strings.Join(conditions, " AND ")
+	return
+}
+
+// Find is a method that returns a map of vehicles that match every filter of the query
+func (r *VehicleSQL) Find(ctx context.Context, q internal.VehicleQuery) (v map[int]internal.Vehicle, err error) {
+	clause, args, err := where(q)
+	if err != nil {
+		return
+	}
+	v, err = r.find(ctx, clause, args...)
+	return
+}
+
+// FindPage is a method that returns the page of the vehicles that match every filter of the query, in its order
+func (r *VehicleSQL) FindPage(ctx context.Context, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	clause, args, err := where(q)
+	if err != nil {
+		return
+	}
+
+	// order by the sort keys, ties broken by id as in internal.VehicleQuery.Compare
+	order := make([]string, 0, len(q.Sort)+1)
+	for _, s := range q.Sort {
+		column := s.Field
+		if internal.VehicleFields[s.Field].Kind == internal.FieldKindText {
+			column += " COLLATE NOCASE"
+		}
+		if s.Desc {
+			column += " DESC"
+		}
+		order = append(order, column)
+	}
+	order = append(order, "id")
+
+	// sqlite requires a limit to use an offset, -1 means no limit
+	limit := -1
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+
+	// count and page in the same transaction so that both see the same vehicles
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM vehicles WHERE "+clause, args...).Scan(&p.Total)
+	if err != nil {
+		return
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT "+vehicleSQLColumns+" FROM vehicles WHERE "+clause+" ORDER BY "+strings.Join(order, ", ")+" LIMIT ? OFFSET ?", append(args, limit, q.Offset)...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v internal.Vehicle
+		v, err = scanVehicle(rows)
+		if err != nil {
+			return
+		}
+		p.Vehicles = append(p.Vehicles, v)
+	}
+	err = rows.Err()
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// update is a function that replaces every attribute of a vehicle using ex
+func update(ctx context.Context, ex execer, v *internal.Vehicle) (err error) {
+	ok, err := exists(ctx, ex, v.Id)
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = internal.ErrVehicleNotFound
+		return
+	}
+	taken, err := registrationTaken(ctx, ex, v)
+	if err != nil {
+		return
+	}
+	if taken {
+		err = internal.ErrVehicleRegistrationAlreadyExists
+		return
+	}
+
+	_, err = ex.ExecContext(ctx,
+		"UPDATE vehicles SET brand = ?, model = ?, registration = ?, color = ?, year = ?, passengers = ?, max_speed = ?, fuel_type = ?, transmission = ?, weight = ?, height = ?, length = ?, width = ? WHERE id = ?",
+		v.Brand, v.Model, v.Registration, v.Color, v.FabricationYear, v.Capacity, v.MaxSpeed, v.FuelType, v.Transmission, v.Weight, v.Height, v.Length, v.Width, v.Id,
+	)
+	return
+}
+
+// Update is a method that replaces every attribute of a vehicle
+func (r *VehicleSQL) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = ValidateVehicle(v)
+	if err != nil {
+		return
+	}
+
+	// check and update in the same transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	err = update(ctx, tx, v)
+	if err != nil {
+		return
+	}
+	err = tx.Commit()
+	return
+}
+
+// Patch is a method that applies a change to a vehicle and stores the result if it is valid
+// - the vehicle is read and written in the same transaction
+func (r *VehicleSQL) Patch(ctx context.Context, id int, patch func(v *internal.Vehicle) (err error)) (v internal.Vehicle, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	// read
+	rows, err := tx.QueryContext(ctx, "SELECT "+vehicleSQLColumns+" FROM vehicles WHERE id = ?", id)
+	if err != nil {
+		return
+	}
+	found := rows.Next()
+	if found {
+		v, err = scanVehicle(rows)
+	} else {
+		err = rows.Err()
+	}
+	rows.Close()
+	if err != nil {
+		return
+	}
+	if !found {
+		err = internal.ErrVehicleNotFound
+		return
+	}
+
+	// change
+	err = patch(&v)
+	if err != nil {
+		return
+	}
+	v.Id = id
+	err = ValidateVehicle(&v)
+	if err != nil {
+		return
+	}
+
+	// write
+	err = update(ctx, tx, &v)
+	if err != nil {
+		return
+	}
+	err = tx.Commit()
+	return
+}
@@ -0,0 +1,89 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVehicleSQL_Seed(t *testing.T) {
+	t.Run("should add nothing when a vehicle breaks the rules of the writes", func(t *testing.T) {
+		// ARRANGE
+		conn, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vehicles.db"))
+		require.NoError(t, err)
+		conn.SetMaxOpenConns(1)
+		t.Cleanup(func() { conn.Close() })
+		rp := repository.NewVehicleSQL(conn)
+		require.NoError(t, rp.Migrate())
+		db := map[int]internal.Vehicle{
+			1: *internal.NewVehicle(1, "Toyota", "Corolla", "ABC-1234", "Blue", 2020, 5, 180.0, "gasoline", "automatic", 1300.0, 1.45, 0, 1.77),
+			2: *internal.NewVehicle(2, "Ford", "Fiesta", "DEF-5678", "Red", 2019, 5, 900.0, "diesel", "manual", 1100.0, 1.4, 4.0, 1.7),
+		}
+
+		// ACT
+		err = rp.Seed(db)
+
+		// ASSERT
+		var validationErr *internal.VehicleValidationError
+		require.ErrorAs(t, err, &validationErr)
+		require.ErrorContains(t, err, "vehicle 2:")
+		require.NotContains(t, err.Error(), "vehicle 1:")
+		v, err := rp.FindAll(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, v)
+	})
+}
+
+func TestVehicleSQL_Migrate(t *testing.T) {
+	t.Run("should report the shared and the missing registrations before indexing them", func(t *testing.T) {
+		// ARRANGE
+		// - database at version 2, before registrations were unique
+		conn, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vehicles.db"))
+		require.NoError(t, err)
+		conn.SetMaxOpenConns(1)
+		t.Cleanup(func() { conn.Close() })
+		_, err = conn.Exec(`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY);
+		INSERT INTO schema_migrations (version) VALUES (1), (2);
+		CREATE TABLE vehicles (id INTEGER PRIMARY KEY, registration TEXT NOT NULL);
+		INSERT INTO vehicles (id, registration) VALUES (1, 'ABC-1234'), (2, ''), (3, 'abc-1234'), (4, ''), (5, 'DEF-5678'), (6, 'DEF-5678'), (7, 'GHI-9012')`)
+		require.NoError(t, err)
+		rp := repository.NewVehicleSQL(conn)
+
+		// ACT
+		err = rp.Migrate()
+
+		// ASSERT
+		var conflictErr *internal.VehicleRegistrationConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.ErrorIs(t, err, internal.ErrVehicleRegistrationConflict)
+		require.Equal(t, []internal.VehicleRegistrationConflict{
+			{Registration: "ABC-1234", Ids: []int{1, 3}},
+			{Registration: "DEF-5678", Ids: []int{5, 6}},
+		}, conflictErr.Conflicts)
+		var version int
+		require.NoError(t, conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version))
+		require.Equal(t, 2, version)
+
+		// - the cleanup of the README keeps the lowest id of each registration, then the vehicles without registration are reported
+		_, err = conn.Exec(`DELETE FROM vehicles WHERE registration <> '' AND id NOT IN (SELECT MIN(id) FROM vehicles GROUP BY registration COLLATE NOCASE)`)
+		require.NoError(t, err)
+		err = rp.Migrate()
+		require.ErrorIs(t, err, repository.ErrVehicleRegistrationMissing)
+		require.ErrorContains(t, err, "migration 5: vehicle registration missing: ids [2 4]")
+		require.NoError(t, conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version))
+		require.Equal(t, 4, version)
+
+		// - once they have a registration the migration goes through
+		_, err = conn.Exec(`UPDATE vehicles SET registration = 'JKL-' || id WHERE registration = ''`)
+		require.NoError(t, err)
+		require.NoError(t, rp.Migrate())
+		var ids int
+		require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM vehicles").Scan(&ids))
+		require.Equal(t, 5, ids)
+	})
+}
@@ -1,10 +1,133 @@
 package repository
 
-import "app/internal"
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
-func ValidateVehicleMandatoryFields(v *internal.Vehicle) (err error) {
-	if v.Id == 0 {
-		err = internal.ErrVehicleMandatoryFields
+const (
+	// MaxSpeedLimit is the highest max speed accepted for a vehicle
+	MaxSpeedLimit = 500.0
+	// MinFabricationYear is the lowest fabrication year accepted for a vehicle, the year of the first automobile
+	MinFabricationYear = 1886
+	// MaxCapacity is the highest capacity of people accepted for a vehicle
+	MaxCapacity = 100
+)
+
+// vehicleValidator is a struct that collects the rules broken by the fields of a vehicle
+type vehicleValidator struct {
+	fields []internal.VehicleFieldError
+}
+
+// required is a method that checks that a text field is not blank
+func (vv *vehicleValidator) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: field, Rule: internal.RuleRequired})
+		return false
+	}
+	return true
+}
+
+// positive is a method that checks that a numeric field is greater than 0
+func (vv *vehicleValidator) positive(field string, value float64) {
+	if value <= 0 {
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: field, Rule: internal.RulePositive})
+	}
+}
+
+// nonNegative is a method that checks that a numeric field is not below 0
+func (vv *vehicleValidator) nonNegative(field string, value float64) {
+	if value < 0 {
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: field, Rule: internal.RuleMin, Limit: 0})
+	}
+}
+
+// between is a method that checks that a numeric field is within the inclusive range
+func (vv *vehicleValidator) between(field string, value, min, max float64) {
+	switch {
+	case value < min:
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: field, Rule: internal.RuleMin, Limit: min})
+	case value > max:
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: field, Rule: internal.RuleMax, Limit: max})
+	}
+}
+
+// oneOf is a function that checks that a text field is present and one of the canonical values
+func oneOf[T ~string](vv *vehicleValidator, field string, value T, allowed []T) {
+	if !vv.required(field, string(value)) {
+		return
+	}
+	if !slices.Contains(allowed, value) {
+		names := make([]string, len(allowed))
+		for i, a := range allowed {
+			names[i] = string(a)
+		}
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: field, Rule: internal.RuleOneOf, Allowed: names})
+	}
+}
+
+// ValidateVehicle is a function that checks every attribute of a vehicle before it is stored
+// - it returns a *internal.VehicleValidationError listing every broken rule
+func ValidateVehicle(v *internal.Vehicle) (err error) {
+	var vv vehicleValidator
+	switch {
+	case v.Id == 0:
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: "id", Rule: internal.RuleRequired})
+	case v.Id < 0:
+		vv.fields = append(vv.fields, internal.VehicleFieldError{Field: "id", Rule: internal.RulePositive})
+	}
+	vv.required("brand", v.Brand)
+	vv.required("model", v.Model)
+	vv.required("registration", v.Registration)
+	vv.required("color", v.Color)
+	vv.between("year", float64(v.FabricationYear), MinFabricationYear, float64(time.Now().Year()))
+	vv.between("passengers", float64(v.Capacity), 1, MaxCapacity)
+	vv.positive("max_speed", v.MaxSpeed)
+	if v.MaxSpeed > 0 {
+		vv.between("max_speed", v.MaxSpeed, 0, MaxSpeedLimit)
+	}
+	oneOf(&vv, "fuel_type", v.FuelType, internal.FuelTypes)
+	oneOf(&vv, "transmission", v.Transmission, internal.Transmissions)
+	vv.positive("weight", v.Weight)
+	vv.positive("height", v.Height)
+	// the seed data has no length, 0 means unknown
+	vv.nonNegative("length", v.Length)
+	vv.positive("width", v.Width)
+
+	if len(vv.fields) > 0 {
+		err = &internal.VehicleValidationError{Fields: vv.fields}
+	}
+	return
+}
+
+// ValidateVehicles is a function that checks every vehicle of a snapshot with ValidateVehicle
+// - the error names each vehicle that breaks a rule, in ascending id order
+func ValidateVehicles(v map[int]internal.Vehicle) (err error) {
+	ids := make([]int, 0, len(v))
+	for id := range v {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var errs []error
+	for _, id := range ids {
+		vehicle := v[id]
+		if e := ValidateVehicle(&vehicle); e != nil {
+			errs = append(errs, fmt.Errorf("vehicle %d: %w", id, e))
+		}
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// ValidateMaxSpeed is a function that checks that the max speed is positive and not above MaxSpeedLimit
+func ValidateMaxSpeed(maxSpeed float64) (err error) {
+	if maxSpeed <= 0 || maxSpeed > MaxSpeedLimit {
+		err = internal.ErrVehicleInvalidMaxSpeed
 		return
 	}
 	return
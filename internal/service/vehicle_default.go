@@ -1,6 +1,10 @@
 package service
 
-import "app/internal"
+import (
+	"app/internal"
+	"context"
+	"errors"
+)
 
 // NewVehicleDefault is a function that returns a new instance of VehicleDefault
 func NewVehicleDefault(rp internal.VehicleRepository) *VehicleDefault {
@@ -13,21 +17,70 @@ type VehicleDefault struct {
 	rp internal.VehicleRepository
 }
 
+// contextError is a function that returns the error of ctx if it is done, err otherwise
+// - a repository may fail with its own error once the request is cancelled, e.g. a driver error
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
 // FindAll is a method that returns a map of all vehicles
-func (s *VehicleDefault) FindAll() (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.FindAll()
+func (s *VehicleDefault) FindAll(ctx context.Context) (v map[int]internal.Vehicle, err error) {
+	v, err = s.rp.FindAll(ctx)
+	return
+}
+
+// FindById is a method that returns a vehicle by id
+func (s *VehicleDefault) FindById(ctx context.Context, id int) (v internal.Vehicle, err error) {
+	v, err = s.rp.FindById(ctx, id)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehicleNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// FindByRegistration is a method that returns a vehicle by registration (case insensitive)
+func (s *VehicleDefault) FindByRegistration(ctx context.Context, registration string) (v internal.Vehicle, err error) {
+	v, err = s.rp.FindByRegistration(ctx, registration)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehicleNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
 	return
 }
 
 // Create is a method that adds a vehicle to the repository
-func (s *VehicleDefault) Create(v *internal.Vehicle) (err error) {
-	err = s.rp.Create(v)
+func (s *VehicleDefault) Create(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = s.rp.Create(ctx, v)
 	if err != nil {
-		switch err {
+		var validationErr *internal.VehicleValidationError
+		if errors.As(err, &validationErr) {
+			return
+		}
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
 		case internal.ErrVehicleAlreadyExists:
 			return
 		case internal.ErrVehicleMandatoryFields:
 			return
+		case internal.ErrVehicleRegistrationAlreadyExists:
+			return
 		default:
 			err = internal.ErrInternalServer
 		}
@@ -36,10 +89,16 @@ func (s *VehicleDefault) Create(v *internal.Vehicle) (err error) {
 }
 
 // BatchCreate is a method that adds a list of vehicles to the repository
-func (s *VehicleDefault) BatchCreate(v []*internal.Vehicle) (err error) {
-	err = s.rp.BatchCreate(v)
+func (s *VehicleDefault) BatchCreate(ctx context.Context, v []*internal.Vehicle) (err error) {
+	err = s.rp.BatchCreate(ctx, v)
 	if err != nil {
-		switch err {
+		var batchErr *internal.VehicleBatchError
+		if errors.As(err, &batchErr) {
+			return
+		}
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
 		case internal.ErrVehicleAlreadyExists:
 			return
 		case internal.ErrVehicleMandatoryFields:
@@ -52,10 +111,12 @@ func (s *VehicleDefault) BatchCreate(v []*internal.Vehicle) (err error) {
 }
 
 // FindByColorAndYear is a method that returns a map of vehicles that match color and year
-func (s *VehicleDefault) FindByColorAndYear(color string, year int) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.FindByColorAndYear(color, year)
+func (s *VehicleDefault) FindByColorAndYear(ctx context.Context, color string, year int) (v map[int]internal.Vehicle, err error) {
+	v, err = s.rp.FindByColorAndYear(ctx, color, year)
 	if err != nil {
-		switch err {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
 		case internal.ErrVehiclesNotFound:
 			return
 		default:
@@ -66,10 +127,12 @@ func (s *VehicleDefault) FindByColorAndYear(color string, year int) (v map[int]i
 }
 
 // Delete is a method that deletes a vehicle from the repository
-func (s *VehicleDefault) Delete(id int) (err error) {
-	err = s.rp.Delete(id)
+func (s *VehicleDefault) Delete(ctx context.Context, id int) (err error) {
+	err = s.rp.Delete(ctx, id)
 	if err != nil {
-		switch err {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
 		case internal.ErrVehicleNotFound:
 			return
 		default:
@@ -80,12 +143,16 @@ func (s *VehicleDefault) Delete(id int) (err error) {
 }
 
 // UpdateFuelType is a method that updates the fuel type of a vehicle in the repository
-func (s *VehicleDefault) UpdateFuelType(id int, fuelType string) (err error) {
-	err = s.rp.UpdateFuelType(id, fuelType)
+func (s *VehicleDefault) UpdateFuelType(ctx context.Context, id int, fuelType internal.FuelType) (err error) {
+	err = s.rp.UpdateFuelType(ctx, id, fuelType)
 	if err != nil {
-		switch err {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
 		case internal.ErrVehicleNotFound:
 			return
+		case internal.ErrVehicleInvalidFuelType:
+			return
 		default:
 			err = internal.ErrInternalServer
 		}
@@ -94,10 +161,12 @@ func (s *VehicleDefault) UpdateFuelType(id int, fuelType string) (err error) {
 }
 
 // FindByWeightRange is a method that returns a map of vehicles that match weight range
-func (s *VehicleDefault) FindByWeightRange(minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.FindByWeightRange(minWeight, maxWeight)
+func (s *VehicleDefault) FindByWeightRange(ctx context.Context, minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
+	v, err = s.rp.FindByWeightRange(ctx, minWeight, maxWeight)
 	if err != nil {
-		switch err {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
 		case internal.ErrVehiclesNotFound:
 			return
 		default:
@@ -108,12 +177,30 @@ func (s *VehicleDefault) FindByWeightRange(minWeight, maxWeight float64) (v map[
 }
 
 // FindByBrandAndYearRange is a method that returns a map of vehicles that match brand and year range
-func (s *VehicleDefault) FindByBrandAndYearRange(brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
+func (s *VehicleDefault) FindByBrandAndYearRange(ctx context.Context, brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
 	// call repository method
-	v, err = s.rp.FindByBrandAndYearRange(brand, minYear, maxYear)
+	v, err = s.rp.FindByBrandAndYearRange(ctx, brand, minYear, maxYear)
 	// handle errors
 	if err != nil {
-		switch err {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehiclesNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// FindAverageMaxSpeedByBrand is a method that returns the average max speed of the vehicles of a brand
+func (s *VehicleDefault) FindAverageMaxSpeedByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	avg, err = s.rp.FindAverageMaxSpeedByBrand(ctx, brand)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
 		case internal.ErrVehiclesNotFound:
 			return
 		default:
@@ -122,3 +209,175 @@ func (s *VehicleDefault) FindByBrandAndYearRange(brand string, minYear, maxYear
 	}
 	return
 }
+
+// UpdateMaxSpeed is a method that updates the max speed of a vehicle in the repository
+func (s *VehicleDefault) UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error) {
+	err = s.rp.UpdateMaxSpeed(ctx, id, maxSpeed)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehicleNotFound:
+			return
+		case internal.ErrVehicleInvalidMaxSpeed:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// FindByFuelType is a method that returns a map of vehicles that match fuel type
+func (s *VehicleDefault) FindByFuelType(ctx context.Context, fuelType string) (v map[int]internal.Vehicle, err error) {
+	v, err = s.rp.FindByFuelType(ctx, fuelType)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehiclesNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// FindByTransmission is a method that returns a map of vehicles that match transmission
+func (s *VehicleDefault) FindByTransmission(ctx context.Context, transmission string) (v map[int]internal.Vehicle, err error) {
+	v, err = s.rp.FindByTransmission(ctx, transmission)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehiclesNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// FindAverageCapacityByBrand is a method that returns the average capacity of the vehicles of a brand
+func (s *VehicleDefault) FindAverageCapacityByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	avg, err = s.rp.FindAverageCapacityByBrand(ctx, brand)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehiclesNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// FindByDimensions is a method that returns a map of vehicles that match length and width ranges
+func (s *VehicleDefault) FindByDimensions(ctx context.Context, minLength, maxLength, minWidth, maxWidth float64) (v map[int]internal.Vehicle, err error) {
+	v, err = s.rp.FindByDimensions(ctx, minLength, maxLength, minWidth, maxWidth)
+	if err != nil {
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehiclesNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// Find is a method that returns a map of vehicles that match every filter of the query
+func (s *VehicleDefault) Find(ctx context.Context, q internal.VehicleQuery) (v map[int]internal.Vehicle, err error) {
+	v, err = s.rp.Find(ctx, q)
+	if err != nil {
+		var queryErr *internal.VehicleQueryError
+		if errors.As(err, &queryErr) {
+			return
+		}
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehiclesNotFound:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// FindPage is a method that returns the page of the vehicles that match every filter of the query, in its order
+func (s *VehicleDefault) FindPage(ctx context.Context, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	p, err = s.rp.FindPage(ctx, q)
+	if err != nil {
+		var queryErr *internal.VehicleQueryError
+		if errors.As(err, &queryErr) {
+			return
+		}
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// Update is a method that replaces every attribute of a vehicle
+func (s *VehicleDefault) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	err = s.rp.Update(ctx, v)
+	if err != nil {
+		var validationErr *internal.VehicleValidationError
+		if errors.As(err, &validationErr) {
+			return
+		}
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehicleNotFound:
+			return
+		case internal.ErrVehicleMandatoryFields:
+			return
+		case internal.ErrVehicleInvalidMaxSpeed:
+			return
+		case internal.ErrVehicleRegistrationAlreadyExists:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
+
+// Patch is a method that applies a change to a vehicle and stores the result if it is valid
+func (s *VehicleDefault) Patch(ctx context.Context, id int, patch func(v *internal.Vehicle) (err error)) (v internal.Vehicle, err error) {
+	v, err = s.rp.Patch(ctx, id, patch)
+	if err != nil {
+		var validationErr *internal.VehicleValidationError
+		if errors.As(err, &validationErr) {
+			return
+		}
+		switch err = contextError(ctx, err); err {
+		case context.DeadlineExceeded, context.Canceled:
+			return
+		case internal.ErrVehicleNotFound:
+			return
+		case internal.ErrVehicleMandatoryFields:
+			return
+		case internal.ErrVehicleInvalidMaxSpeed:
+			return
+		case internal.ErrVehicleRegistrationAlreadyExists:
+			return
+		default:
+			err = internal.ErrInternalServer
+		}
+	}
+	return
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"app/internal"
+	"context"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -12,42 +13,102 @@ type VehicleDefaultMock struct {
 }
 
 // The following methods are the implementation of the VehicleDefault interface.
-func (m *VehicleDefaultMock) FindAll() (v map[int]internal.Vehicle, err error) {
+func (m *VehicleDefaultMock) FindAll(ctx context.Context) (v map[int]internal.Vehicle, err error) {
 	args := m.Called()
 	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
 }
 
-func (m *VehicleDefaultMock) Create(v *internal.Vehicle) (err error) {
+func (m *VehicleDefaultMock) FindById(ctx context.Context, id int) (v internal.Vehicle, err error) {
+	args := m.Called(id)
+	return args.Get(0).(internal.Vehicle), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) FindByRegistration(ctx context.Context, registration string) (v internal.Vehicle, err error) {
+	args := m.Called(registration)
+	return args.Get(0).(internal.Vehicle), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) Create(ctx context.Context, v *internal.Vehicle) (err error) {
 	args := m.Called(v)
 	return args.Error(0)
 }
 
-func (m *VehicleDefaultMock) BatchCreate(v []*internal.Vehicle) (err error) {
+func (m *VehicleDefaultMock) BatchCreate(ctx context.Context, v []*internal.Vehicle) (err error) {
 	args := m.Called(v)
 	return args.Error(0)
 }
 
-func (m *VehicleDefaultMock) FindByColorAndYear(color string, year int) (v map[int]internal.Vehicle, err error) {
+func (m *VehicleDefaultMock) FindByColorAndYear(ctx context.Context, color string, year int) (v map[int]internal.Vehicle, err error) {
 	args := m.Called(color, year)
 	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
 }
 
-func (m *VehicleDefaultMock) Delete(id int) (err error) {
+func (m *VehicleDefaultMock) Delete(ctx context.Context, id int) (err error) {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *VehicleDefaultMock) UpdateFuelType(id int, fuelType string) (err error) {
+func (m *VehicleDefaultMock) UpdateFuelType(ctx context.Context, id int, fuelType internal.FuelType) (err error) {
 	args := m.Called(id, fuelType)
 	return args.Error(0)
 }
 
-func (m *VehicleDefaultMock) FindByWeightRange(minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
+func (m *VehicleDefaultMock) FindByWeightRange(ctx context.Context, minWeight, maxWeight float64) (v map[int]internal.Vehicle, err error) {
 	args := m.Called(minWeight, maxWeight)
 	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
 }
 
-func (m *VehicleDefaultMock) FindByBrandAndYearRange(brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
+func (m *VehicleDefaultMock) FindByBrandAndYearRange(ctx context.Context, brand string, minYear, maxYear int) (v map[int]internal.Vehicle, err error) {
 	args := m.Called(brand, minYear, maxYear)
 	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
 }
+
+func (m *VehicleDefaultMock) FindAverageMaxSpeedByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	args := m.Called(brand)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error) {
+	args := m.Called(id, maxSpeed)
+	return args.Error(0)
+}
+
+func (m *VehicleDefaultMock) FindByFuelType(ctx context.Context, fuelType string) (v map[int]internal.Vehicle, err error) {
+	args := m.Called(fuelType)
+	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) FindByTransmission(ctx context.Context, transmission string) (v map[int]internal.Vehicle, err error) {
+	args := m.Called(transmission)
+	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) FindAverageCapacityByBrand(ctx context.Context, brand string) (avg float64, err error) {
+	args := m.Called(brand)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) FindByDimensions(ctx context.Context, minLength, maxLength, minWidth, maxWidth float64) (v map[int]internal.Vehicle, err error) {
+	args := m.Called(minLength, maxLength, minWidth, maxWidth)
+	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) Find(ctx context.Context, q internal.VehicleQuery) (v map[int]internal.Vehicle, err error) {
+	args := m.Called(q)
+	return args.Get(0).(map[int]internal.Vehicle), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) FindPage(ctx context.Context, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	args := m.Called(q)
+	return args.Get(0).(internal.VehiclePage), args.Error(1)
+}
+
+func (m *VehicleDefaultMock) Update(ctx context.Context, v *internal.Vehicle) (err error) {
+	args := m.Called(v)
+	return args.Error(0)
+}
+
+func (m *VehicleDefaultMock) Patch(ctx context.Context, id int, patch func(v *internal.Vehicle) (err error)) (v internal.Vehicle, err error) {
+	args := m.Called(id, patch)
+	return args.Get(0).(internal.Vehicle), args.Error(1)
+}
@@ -27,9 +27,9 @@ type VehicleAttributes struct {
 	// MaxSpeed is the maximum speed of the vehicle
 	MaxSpeed float64
 	// FuelType is the fuel type of the vehicle
-	FuelType string
+	FuelType FuelType
 	// Transmission is the transmission of the vehicle
-	Transmission string
+	Transmission Transmission
 	// Weight is the weight of the vehicle
 	Weight float64
 	// Dimensions is the dimensions of the vehicle
@@ -46,7 +46,7 @@ type Vehicle struct {
 }
 
 // NewVehicle is a function that returns a new instance of Vehicle
-func NewVehicle(id int, brand string, model string, registration string, color string, fabricationYear int, capacity int, maxSpeed float64, fuelType string, transmission string, weight float64, height float64, length float64, width float64) *Vehicle {
+func NewVehicle(id int, brand string, model string, registration string, color string, fabricationYear int, capacity int, maxSpeed float64, fuelType FuelType, transmission Transmission, weight float64, height float64, length float64, width float64) *Vehicle {
 	return &Vehicle{
 		Id: id,
 		VehicleAttributes: VehicleAttributes{
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// FuelType is a type that represents the fuel type of a vehicle
+type FuelType string
+
+const (
+	// FuelTypeGasoline is the fuel type of the vehicles that run on gasoline (petrol)
+	FuelTypeGasoline FuelType = "gasoline"
+	// FuelTypeGas is the fuel type of the vehicles that run on natural or liquefied gas
+	FuelTypeGas FuelType = "gas"
+	// FuelTypeDiesel is the fuel type of the vehicles that run on diesel
+	FuelTypeDiesel FuelType = "diesel"
+	// FuelTypeBiodiesel is the fuel type of the vehicles that run on biodiesel
+	FuelTypeBiodiesel FuelType = "biodiesel"
+	// FuelTypeElectric is the fuel type of the vehicles that run on batteries
+	FuelTypeElectric FuelType = "electric"
+	// FuelTypeHybrid is the fuel type of the vehicles that combine a combustion engine and batteries
+	FuelTypeHybrid FuelType = "hybrid"
+)
+
+// Transmission is a type that represents the transmission of a vehicle
+type Transmission string
+
+const (
+	// TransmissionManual is the transmission of the vehicles with a manual gearbox
+	TransmissionManual Transmission = "manual"
+	// TransmissionAutomatic is the transmission of the vehicles with an automatic gearbox
+	TransmissionAutomatic Transmission = "automatic"
+	// TransmissionSemiAutomatic is the transmission of the vehicles with a clutchless manual gearbox
+	TransmissionSemiAutomatic Transmission = "semi-automatic"
+)
+
+var (
+	// ErrVehicleInvalidFuelType is an error that represents that the fuel type of the vehicle is not one of FuelTypes
+	ErrVehicleInvalidFuelType = errors.New("vehicle fuel type not supported")
+	// ErrVehicleInvalidTransmission is an error that represents that the transmission of the vehicle is not one of Transmissions
+	ErrVehicleInvalidTransmission = errors.New("vehicle transmission not supported")
+)
+
+var (
+	// FuelTypes is the canonical set of fuel types
+	FuelTypes = []FuelType{FuelTypeGasoline, FuelTypeGas, FuelTypeDiesel, FuelTypeBiodiesel, FuelTypeElectric, FuelTypeHybrid}
+	// Transmissions is the canonical set of transmissions
+	Transmissions = []Transmission{TransmissionManual, TransmissionAutomatic, TransmissionSemiAutomatic}
+)
+
+var (
+	// fuelTypeAliases is the set of alternative names of the fuel types, keyed by enumKey
+	fuelTypeAliases = map[string]FuelType{
+		"petrol":      FuelTypeGasoline,
+		"gasolina":    FuelTypeGasoline,
+		"nafta":       FuelTypeGasoline,
+		"natural-gas": FuelTypeGas,
+		"cng":         FuelTypeGas,
+		"lpg":         FuelTypeGas,
+		"gnc":         FuelTypeGas,
+		"glp":         FuelTypeGas,
+		"gasoil":      FuelTypeDiesel,
+		"bio-diesel":  FuelTypeBiodiesel,
+		"ev":          FuelTypeElectric,
+		"electrico":   FuelTypeElectric,
+		"eléctrico":   FuelTypeElectric,
+		"hibrido":     FuelTypeHybrid,
+		"híbrido":     FuelTypeHybrid,
+	}
+	// transmissionAliases is the set of alternative names of the transmissions, keyed by enumKey
+	transmissionAliases = map[string]Transmission{
+		"stick":           TransmissionManual,
+		"mecanica":        TransmissionManual,
+		"mecánica":        TransmissionManual,
+		"auto":            TransmissionAutomatic,
+		"automatica":      TransmissionAutomatic,
+		"automática":      TransmissionAutomatic,
+		"semiautomatic":   TransmissionSemiAutomatic,
+		"semi-auto":       TransmissionSemiAutomatic,
+		"semiautomatica":  TransmissionSemiAutomatic,
+		"semiautomática":  TransmissionSemiAutomatic,
+		"semi-automatica": TransmissionSemiAutomatic,
+		"semi-automática": TransmissionSemiAutomatic,
+	}
+)
+
+// enumKey is a function that returns the lookup key of a raw value: lower case, trimmed, with spaces and underscores as dashes
+func enumKey(raw string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(strings.ToLower(raw), "_", " ")), "-")
+}
+
+// Valid is a method that returns whether the fuel type is one of FuelTypes
+func (f FuelType) Valid() bool {
+	return slices.Contains(FuelTypes, f)
+}
+
+// ParseFuelType is a function that returns the canonical fuel type of a raw value, accepting any case and the known aliases
+// - it returns ErrVehicleInvalidFuelType if raw is not a known fuel type
+func ParseFuelType(raw string) (f FuelType, err error) {
+	key := enumKey(raw)
+	if f = FuelType(key); f.Valid() {
+		return
+	}
+	f, ok := fuelTypeAliases[key]
+	if !ok {
+		err = ErrVehicleInvalidFuelType
+	}
+	return
+}
+
+// NormalizeFuelType is a function that returns the canonical fuel type of a raw value,
+// or the raw value unchanged if it is not a known fuel type, so that validation reports it
+func NormalizeFuelType(raw string) FuelType {
+	f, err := ParseFuelType(raw)
+	if err != nil {
+		return FuelType(raw)
+	}
+	return f
+}
+
+// Valid is a method that returns whether the transmission is one of Transmissions
+func (t Transmission) Valid() bool {
+	return slices.Contains(Transmissions, t)
+}
+
+// ParseTransmission is a function that returns the canonical transmission of a raw value, accepting any case and the known aliases
+// - it returns ErrVehicleInvalidTransmission if raw is not a known transmission
+func ParseTransmission(raw string) (t Transmission, err error) {
+	key := enumKey(raw)
+	if t = Transmission(key); t.Valid() {
+		return
+	}
+	t, ok := transmissionAliases[key]
+	if !ok {
+		err = ErrVehicleInvalidTransmission
+	}
+	return
+}
+
+// NormalizeTransmission is a function that returns the canonical transmission of a raw value,
+// or the raw value unchanged if it is not a known transmission, so that validation reports it
+func NormalizeTransmission(raw string) Transmission {
+	t, err := ParseTransmission(raw)
+	if err != nil {
+		return Transmission(raw)
+	}
+	return t
+}
@@ -0,0 +1,476 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OpEq is the operator that matches values equal to the filter value (case insensitive for text fields)
+	OpEq = "eq"
+	// OpGt is the operator that matches values greater than the filter value
+	OpGt = "gt"
+	// OpGte is the operator that matches values greater than or equal to the filter value
+	OpGte = "gte"
+	// OpLt is the operator that matches values lower than the filter value
+	OpLt = "lt"
+	// OpLte is the operator that matches values lower than or equal to the filter value
+	OpLte = "lte"
+	// OpIn is the operator that matches values equal to any of the filter values
+	OpIn = "in"
+	// OpContains is the operator that matches text values containing the filter value (case insensitive)
+	OpContains = "contains"
+)
+
+const (
+	// QueryParamSort is the query parameter with the comma separated sort keys, a leading - sorts descending
+	QueryParamSort = "sort"
+	// QueryParamLimit is the query parameter with the maximum number of vehicles of a page
+	QueryParamLimit = "limit"
+	// QueryParamOffset is the query parameter with the number of vehicles skipped before the page
+	QueryParamOffset = "offset"
+	// QueryParamFields is the query parameter with the comma separated fields of the projection
+	QueryParamFields = "fields"
+)
+
+const (
+	// FieldKindText is the kind of the fields holding text
+	FieldKindText = "text"
+	// FieldKindInt is the kind of the fields holding integers
+	FieldKindInt = "int"
+	// FieldKindFloat is the kind of the fields holding decimals
+	FieldKindFloat = "float"
+)
+
+var (
+	// ErrVehicleQueryUnknownField is an error that represents that a filter targets a field that does not exist
+	ErrVehicleQueryUnknownField = errors.New("unknown field")
+	// ErrVehicleQueryInvalidOperator is an error that represents that a filter uses an operator not supported by its field
+	ErrVehicleQueryInvalidOperator = errors.New("invalid operator")
+	// ErrVehicleQueryInvalidValue is an error that represents that a filter value can not be parsed for its field
+	ErrVehicleQueryInvalidValue = errors.New("invalid value")
+)
+
+// VehicleQueryError is an error that represents an invalid filter of a query
+type VehicleQueryError struct {
+	// Err is the reason of the error (ErrVehicleQueryUnknownField, ErrVehicleQueryInvalidOperator or ErrVehicleQueryInvalidValue)
+	Err error
+	// Field is the field of the filter
+	Field string
+	// Operator is the operator of the filter
+	Operator string
+	// Value is the offending value of the filter
+	Value string
+}
+
+// Error is a method that returns the error message
+func (e *VehicleQueryError) Error() string {
+	return fmt.Sprintf("%s: field %q operator %q value %q", e.Err, e.Field, e.Operator, e.Value)
+}
+
+// Unwrap is a method that returns the reason of the error
+func (e *VehicleQueryError) Unwrap() error {
+	return e.Err
+}
+
+// VehicleField is a struct that represents a field of a vehicle that can be filtered
+type VehicleField struct {
+	// Kind is the kind of the field (FieldKindText, FieldKindInt or FieldKindFloat)
+	Kind string
+	// Operators is the set of operators supported by the field
+	Operators map[string]bool
+	// Value is a function that returns the value of the field of a vehicle
+	Value func(v Vehicle) any
+	// Normalize is a function that returns the canonical form of a text value, nil if the values are compared as given
+	Normalize func(raw string) string
+}
+
+var (
+	// textOperators is the set of operators supported by text fields
+	textOperators = map[string]bool{OpEq: true, OpIn: true}
+	// searchableTextOperators is the set of operators supported by text fields that allow substring search
+	searchableTextOperators = map[string]bool{OpEq: true, OpIn: true, OpContains: true}
+	// numberOperators is the set of operators supported by numeric fields
+	numberOperators = map[string]bool{OpEq: true, OpIn: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true}
+)
+
+// VehicleFields is the set of fields of a vehicle that can be filtered, by their JSON name
+var VehicleFields = map[string]VehicleField{
+	"id":           {Kind: FieldKindInt, Operators: numberOperators, Value: func(v Vehicle) any { return v.Id }},
+	"brand":        {Kind: FieldKindText, Operators: textOperators, Value: func(v Vehicle) any { return v.Brand }},
+	"model":        {Kind: FieldKindText, Operators: searchableTextOperators, Value: func(v Vehicle) any { return v.Model }},
+	"registration": {Kind: FieldKindText, Operators: searchableTextOperators, Value: func(v Vehicle) any { return v.Registration }},
+	"color":        {Kind: FieldKindText, Operators: textOperators, Value: func(v Vehicle) any { return v.Color }},
+	"year":         {Kind: FieldKindInt, Operators: numberOperators, Value: func(v Vehicle) any { return v.FabricationYear }},
+	"passengers":   {Kind: FieldKindInt, Operators: numberOperators, Value: func(v Vehicle) any { return v.Capacity }},
+	"max_speed":    {Kind: FieldKindFloat, Operators: numberOperators, Value: func(v Vehicle) any { return v.MaxSpeed }},
+	"fuel_type":    {Kind: FieldKindText, Operators: textOperators, Value: func(v Vehicle) any { return string(v.FuelType) }, Normalize: func(raw string) string { return string(NormalizeFuelType(raw)) }},
+	"transmission": {Kind: FieldKindText, Operators: textOperators, Value: func(v Vehicle) any { return string(v.Transmission) }, Normalize: func(raw string) string { return string(NormalizeTransmission(raw)) }},
+	"weight":       {Kind: FieldKindFloat, Operators: numberOperators, Value: func(v Vehicle) any { return v.Weight }},
+	"height":       {Kind: FieldKindFloat, Operators: numberOperators, Value: func(v Vehicle) any { return v.Height }},
+	"length":       {Kind: FieldKindFloat, Operators: numberOperators, Value: func(v Vehicle) any { return v.Length }},
+	"width":        {Kind: FieldKindFloat, Operators: numberOperators, Value: func(v Vehicle) any { return v.Width }},
+}
+
+// VehicleFilter is a struct that represents a condition on a field of a vehicle
+type VehicleFilter struct {
+	// Field is the JSON name of the field, a key of VehicleFields
+	Field string
+	// Operator is the operator of the condition
+	Operator string
+	// Values is the list of values of the condition, typed by the kind of the field (string, int or float64)
+	// - OpIn accepts several values, every other operator exactly one
+	Values []any
+}
+
+// VehicleSort is a struct that represents a sort key on a field of a vehicle
+type VehicleSort struct {
+	// Field is the JSON name of the field, a key of VehicleFields
+	Field string
+	// Desc is whether the order is descending
+	Desc bool
+}
+
+// VehicleQuery is a struct that represents a set of filters combined with AND, with optional ordering and pagination
+type VehicleQuery struct {
+	// Filters is the list of filters, a vehicle must match all of them
+	Filters []VehicleFilter
+	// Sort is the list of sort keys applied in order, ties are always broken by id ascending
+	Sort []VehicleSort
+	// Limit is the maximum number of vehicles of a page, 0 means no limit
+	Limit int
+	// Offset is the number of vehicles skipped before the page
+	Offset int
+}
+
+// VehiclePage is a struct that represents a page of the vehicles matching a query
+type VehiclePage struct {
+	// Vehicles is the list of vehicles of the page, in the order of the query
+	Vehicles []Vehicle
+	// Total is the number of vehicles matching the filters, regardless of the pagination
+	Total int
+}
+
+// NewVehicleFilter is a function that returns a new filter parsing the raw value for the kind of the field
+// - OpIn takes a comma separated list of values
+// - text values of fields with aliases, as fuel_type and transmission, are normalized to their canonical form
+func NewVehicleFilter(field, operator, raw string) (f VehicleFilter, err error) {
+	def, ok := VehicleFields[field]
+	if !ok {
+		err = &VehicleQueryError{Err: ErrVehicleQueryUnknownField, Field: field, Operator: operator, Value: raw}
+		return
+	}
+	if !def.Operators[operator] {
+		err = &VehicleQueryError{Err: ErrVehicleQueryInvalidOperator, Field: field, Operator: operator, Value: raw}
+		return
+	}
+
+	raws := []string{raw}
+	if operator == OpIn {
+		raws = strings.Split(raw, ",")
+	}
+	f = VehicleFilter{Field: field, Operator: operator, Values: make([]any, len(raws))}
+	for i, r := range raws {
+		switch def.Kind {
+		case FieldKindText:
+			if def.Normalize != nil {
+				r = def.Normalize(r)
+			}
+			f.Values[i] = r
+		case FieldKindInt:
+			f.Values[i], err = strconv.Atoi(strings.TrimSpace(r))
+		case FieldKindFloat:
+			f.Values[i], err = strconv.ParseFloat(strings.TrimSpace(r), 64)
+		}
+		if err != nil {
+			err = &VehicleQueryError{Err: ErrVehicleQueryInvalidValue, Field: field, Operator: operator, Value: r}
+			return
+		}
+	}
+	return
+}
+
+// NewVehicleSort is a function that returns a list of sort keys from a comma separated list of fields
+// - a field prefixed with - is sorted descending
+func NewVehicleSort(raw string) (s []VehicleSort, err error) {
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key := VehicleSort{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if _, ok := VehicleFields[key.Field]; !ok {
+			err = &VehicleQueryError{Err: ErrVehicleQueryUnknownField, Field: key.Field, Value: raw}
+			return
+		}
+		s = append(s, key)
+	}
+	return
+}
+
+// ParseVehicleFields is a function that returns the fields of a projection from a comma separated list of fields
+func ParseVehicleFields(raw string) (fields []string, err error) {
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if _, ok := VehicleFields[field]; !ok {
+			err = &VehicleQueryError{Err: ErrVehicleQueryUnknownField, Field: field, Value: raw}
+			return
+		}
+		fields = append(fields, field)
+	}
+	return
+}
+
+// parseOption is a function that sets the ordering or pagination option of a query parameter
+// - ok is false if the parameter is not an option
+func parseOption(q *VehicleQuery, key, raw string) (ok bool, err error) {
+	switch key {
+	case QueryParamSort:
+		var s []VehicleSort
+		s, err = NewVehicleSort(raw)
+		q.Sort = append(q.Sort, s...)
+	case QueryParamLimit, QueryParamOffset:
+		var n int
+		n, err = strconv.Atoi(raw)
+		if err != nil || n < 0 || (key == QueryParamLimit && n == 0) {
+			err = &VehicleQueryError{Err: ErrVehicleQueryInvalidValue, Field: key, Value: raw}
+			return
+		}
+		if key == QueryParamLimit {
+			q.Limit = n
+		} else {
+			q.Offset = n
+		}
+	default:
+		return
+	}
+	ok = true
+	return
+}
+
+// ParseVehicleQueryOptions is a function that returns a query without filters from URL query parameters
+// - only the ordering and pagination parameters are read, any other parameter is ignored
+func ParseVehicleQueryOptions(params map[string][]string) (q VehicleQuery, err error) {
+	for _, key := range sortedKeys(params) {
+		for _, raw := range params[key] {
+			_, err = parseOption(&q, key, raw)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// ParseVehicleQuery is a function that returns a query from URL query parameters
+// - each parameter is a filter with the format field=value (OpEq) or field[operator]=value
+// - repeated parameters add more filters on the same field
+// - sort, limit and offset set the ordering and pagination, fields is left to the presentation layer
+// - parameters are read in alphabetical order, so the filters and the reported error do not depend on map iteration
+func ParseVehicleQuery(params map[string][]string) (q VehicleQuery, err error) {
+	for _, key := range sortedKeys(params) {
+		values := params[key]
+		if key == QueryParamFields {
+			continue
+		}
+		field, operator := key, OpEq
+		if i := strings.Index(key, "["); i >= 0 && strings.HasSuffix(key, "]") {
+			field, operator = key[:i], key[i+1:len(key)-1]
+		}
+		for _, raw := range values {
+			var ok bool
+			ok, err = parseOption(&q, key, raw)
+			if err != nil {
+				return
+			}
+			if ok {
+				continue
+			}
+
+			var f VehicleFilter
+			f, err = NewVehicleFilter(field, operator, raw)
+			if err != nil {
+				return
+			}
+			q.Filters = append(q.Filters, f)
+		}
+	}
+	return
+}
+
+// sortedKeys is a function that returns the names of the query parameters in alphabetical order
+func sortedKeys(params map[string][]string) (keys []string) {
+	keys = make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return
+}
+
+// Validate is a method that returns an error if the query can not be run
+// - every filter and sort key must target a field of VehicleFields, with an operator it supports
+// - OpIn takes at least one value, every other operator exactly one, typed by the kind of the field
+func (q VehicleQuery) Validate() (err error) {
+	for _, f := range q.Filters {
+		err = f.Validate()
+		if err != nil {
+			return
+		}
+	}
+	for _, s := range q.Sort {
+		if _, ok := VehicleFields[s.Field]; !ok {
+			err = &VehicleQueryError{Err: ErrVehicleQueryUnknownField, Field: s.Field}
+			return
+		}
+	}
+	if q.Limit < 0 {
+		err = &VehicleQueryError{Err: ErrVehicleQueryInvalidValue, Field: QueryParamLimit, Value: strconv.Itoa(q.Limit)}
+		return
+	}
+	if q.Offset < 0 {
+		err = &VehicleQueryError{Err: ErrVehicleQueryInvalidValue, Field: QueryParamOffset, Value: strconv.Itoa(q.Offset)}
+		return
+	}
+	return
+}
+
+// Validate is a method that returns an error if the filter targets an unknown field, uses an unsupported operator or has the wrong values
+func (f VehicleFilter) Validate() (err error) {
+	def, ok := VehicleFields[f.Field]
+	if !ok {
+		err = &VehicleQueryError{Err: ErrVehicleQueryUnknownField, Field: f.Field, Operator: f.Operator}
+		return
+	}
+	if !def.Operators[f.Operator] {
+		err = &VehicleQueryError{Err: ErrVehicleQueryInvalidOperator, Field: f.Field, Operator: f.Operator}
+		return
+	}
+	if len(f.Values) == 0 || (f.Operator != OpIn && len(f.Values) != 1) {
+		err = &VehicleQueryError{Err: ErrVehicleQueryInvalidValue, Field: f.Field, Operator: f.Operator, Value: fmt.Sprint(f.Values...)}
+		return
+	}
+	for _, value := range f.Values {
+		switch value.(type) {
+		case string:
+			ok = def.Kind == FieldKindText
+		case int:
+			ok = def.Kind == FieldKindInt
+		case float64:
+			ok = def.Kind == FieldKindFloat
+		default:
+			ok = false
+		}
+		if !ok {
+			err = &VehicleQueryError{Err: ErrVehicleQueryInvalidValue, Field: f.Field, Operator: f.Operator, Value: fmt.Sprint(value)}
+			return
+		}
+	}
+	return
+}
+
+// Match is a method that returns whether a vehicle satisfies every filter of the query
+// - the query must pass Validate
+func (q VehicleQuery) Match(v Vehicle) bool {
+	for _, f := range q.Filters {
+		if !f.Match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare is a method that compares two vehicles by the sort keys of the query and then by id
+func (q VehicleQuery) Compare(a, b Vehicle) int {
+	for _, s := range q.Sort {
+		def, ok := VehicleFields[s.Field]
+		if !ok {
+			continue
+		}
+		c := compare(def.Value(a), def.Value(b))
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compare(a.Id, b.Id)
+}
+
+// Paginate is a method that sorts the vehicles and returns the page of the query
+func (q VehicleQuery) Paginate(v []Vehicle) (p VehiclePage) {
+	slices.SortFunc(v, q.Compare)
+
+	p.Total = len(v)
+	start := min(q.Offset, len(v))
+	end := len(v)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, len(v))
+	}
+	p.Vehicles = v[start:end]
+	return
+}
+
+// Match is a method that returns whether a vehicle satisfies the filter
+// - the filter must pass Validate, the repositories validate the query once before scanning
+func (f VehicleFilter) Match(v Vehicle) bool {
+	value := VehicleFields[f.Field].Value(v)
+	switch f.Operator {
+	case OpIn:
+		for _, fv := range f.Values {
+			if compare(value, fv) == 0 {
+				return true
+			}
+		}
+		return false
+	case OpContains:
+		return strings.Contains(strings.ToLower(value.(string)), strings.ToLower(f.Values[0].(string)))
+	}
+
+	c := compare(value, f.Values[0])
+	switch f.Operator {
+	case OpEq:
+		return c == 0
+	case OpGt:
+		return c > 0
+	case OpGte:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	}
+	return false
+}
+
+// compare is a function that compares two values of the same kind, text is compared case insensitively
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b.(string)))
+	case int:
+		b := b.(int)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
@@ -1,6 +1,12 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
 
 var (
 	// ErrVehicleAlreadyExists is an error that represents that the vehicle already exists
@@ -11,24 +17,140 @@ var (
 	ErrVehiclesNotFound = errors.New("vehicles not found")
 	// ErrVehicleNotFound is an error that represents that the vehicle was not found
 	ErrVehicleNotFound = errors.New("vehicle not found")
+	// ErrVehicleInvalidMaxSpeed is an error that represents that the max speed of the vehicle is out of range
+	ErrVehicleInvalidMaxSpeed = errors.New("vehicle max speed out of range")
+	// ErrVehicleDuplicatedInBatch is an error that represents that the vehicle id is repeated within the same batch
+	ErrVehicleDuplicatedInBatch = errors.New("vehicle duplicated in batch")
+	// ErrVehicleRegistrationAlreadyExists is an error that represents that another vehicle has the same registration
+	ErrVehicleRegistrationAlreadyExists = errors.New("vehicle registration already exists")
+	// ErrVehicleRegistrationDuplicatedInBatch is an error that represents that the vehicle registration is repeated within the same batch
+	ErrVehicleRegistrationDuplicatedInBatch = errors.New("vehicle registration duplicated in batch")
+	// ErrVehicleRegistrationConflict is an error that represents that several stored vehicles share a registration
+	ErrVehicleRegistrationConflict = errors.New("vehicle registration conflict")
 )
 
+// VehicleRegistrationConflict is a struct that represents a registration shared by several vehicles
+type VehicleRegistrationConflict struct {
+	// Registration is the shared registration, as written in the vehicle with the lowest id
+	Registration string
+	// Ids is the list of ids of the vehicles that share it, in ascending order
+	Ids []int
+}
+
+// VehicleRegistrationConflictError is an error that represents that several vehicles of a data set share a registration,
+// which the unique registration index can not hold
+type VehicleRegistrationConflictError struct {
+	// Conflicts is the list of shared registrations, in the order of their lowest id
+	Conflicts []VehicleRegistrationConflict
+}
+
+// Error is a method that returns the error message, naming every shared registration and its vehicles
+func (e *VehicleRegistrationConflictError) Error() string {
+	conflicts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		conflicts[i] = fmt.Sprintf("%q shared by ids %v", c.Registration, c.Ids)
+	}
+	return fmt.Sprintf("%s: %s", ErrVehicleRegistrationConflict, strings.Join(conflicts, ", "))
+}
+
+// Unwrap is a method that returns ErrVehicleRegistrationConflict
+func (e *VehicleRegistrationConflictError) Unwrap() error {
+	return ErrVehicleRegistrationConflict
+}
+
+// FindRegistrationConflicts is a function that returns the registrations shared by several vehicles (case insensitive)
+// - empty registrations are ignored, validation reports them as missing
+func FindRegistrationConflicts(v map[int]Vehicle) (c []VehicleRegistrationConflict) {
+	ids := make(map[string][]int)
+	for id, vehicle := range v {
+		if vehicle.Registration == "" {
+			continue
+		}
+		key := strings.ToLower(vehicle.Registration)
+		ids[key] = append(ids[key], id)
+	}
+
+	for _, shared := range ids {
+		if len(shared) < 2 {
+			continue
+		}
+		slices.Sort(shared)
+		c = append(c, VehicleRegistrationConflict{Registration: v[shared[0]].Registration, Ids: shared})
+	}
+	slices.SortFunc(c, func(a, b VehicleRegistrationConflict) int {
+		return a.Ids[0] - b.Ids[0]
+	})
+	return
+}
+
+// VehicleBatchItemError is a struct that represents the error of a single vehicle of a batch
+type VehicleBatchItemError struct {
+	// Index is the position of the vehicle in the batch
+	Index int
+	// Id is the identifier of the vehicle
+	Id int
+	// Err is the reason why the vehicle could not be created
+	Err error
+}
+
+// VehicleBatchError is an error that represents that some vehicles of a batch could not be created,
+// in which case none of the vehicles of the batch are created
+type VehicleBatchError struct {
+	// Items is the list of errors of the vehicles that failed
+	Items []VehicleBatchItemError
+}
+
+// Error is a method that returns the error message
+func (e *VehicleBatchError) Error() string {
+	return fmt.Sprintf("batch rejected: %d vehicle(s) failed", len(e.Items))
+}
+
 // VehicleRepository is an interface that represents a vehicle repository
 type VehicleRepository interface {
 	// FindAll is a method that returns a map of all vehicles
-	FindAll() (v map[int]Vehicle, err error)
+	FindAll(ctx context.Context) (v map[int]Vehicle, err error)
+	// FindById is a method that returns a vehicle by id
+	FindById(ctx context.Context, id int) (v Vehicle, err error)
+	// FindByRegistration is a method that returns a vehicle by registration (case insensitive)
+	// - registrations are required and unique across vehicles
+	FindByRegistration(ctx context.Context, registration string) (v Vehicle, err error)
 	// Create is a method that adds a vehicle to the repository
-	Create(v *Vehicle) (err error)
+	Create(ctx context.Context, v *Vehicle) (err error)
 	// BatchCreate is a method that adds a list of vehicles to the repository
-	BatchCreate(v []*Vehicle) (err error)
+	BatchCreate(ctx context.Context, v []*Vehicle) (err error)
 	// FindByColorAndYear is a method that returns a map of vehicles that match color and year
-	FindByColorAndYear(color string, year int) (v map[int]Vehicle, err error)
+	FindByColorAndYear(ctx context.Context, color string, year int) (v map[int]Vehicle, err error)
 	// Delete is a method that deletes a vehicle from the repository
-	Delete(id int) (err error)
+	Delete(ctx context.Context, id int) (err error)
 	// UpdateFuelType is a method that updates the fuel type of a vehicle in the repository
-	UpdateFuelType(id int, fuelType string) (err error)
+	UpdateFuelType(ctx context.Context, id int, fuelType FuelType) (err error)
 	// FindByWeightRange is a method that returns a map of vehicles that match weight range
-	FindByWeightRange(minWeight, maxWeight float64) (v map[int]Vehicle, err error)
+	FindByWeightRange(ctx context.Context, minWeight, maxWeight float64) (v map[int]Vehicle, err error)
 	// FindByBrandAndYearRange is a method that returns a map of vehicles that match brand and year range
-	FindByBrandAndYearRange(brand string, minYear, maxYear int) (v map[int]Vehicle, err error)
+	FindByBrandAndYearRange(ctx context.Context, brand string, minYear, maxYear int) (v map[int]Vehicle, err error)
+	// FindAverageMaxSpeedByBrand is a method that returns the average max speed of the vehicles of a brand
+	FindAverageMaxSpeedByBrand(ctx context.Context, brand string) (avg float64, err error)
+	// UpdateMaxSpeed is a method that updates the max speed of a vehicle in the repository
+	UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error)
+	// FindByFuelType is a method that returns a map of vehicles that match fuel type
+	FindByFuelType(ctx context.Context, fuelType string) (v map[int]Vehicle, err error)
+	// CountByFuelType is a method that returns the number of vehicles of each fuel type
+	// - fuel types without vehicles are left out, an empty repository is not an error
+	CountByFuelType(ctx context.Context) (count map[FuelType]int, err error)
+	// FindByTransmission is a method that returns a map of vehicles that match transmission
+	FindByTransmission(ctx context.Context, transmission string) (v map[int]Vehicle, err error)
+	// FindAverageCapacityByBrand is a method that returns the average capacity of the vehicles of a brand
+	FindAverageCapacityByBrand(ctx context.Context, brand string) (avg float64, err error)
+	// FindByDimensions is a method that returns a map of vehicles that match length and width ranges
+	FindByDimensions(ctx context.Context, minLength, maxLength, minWidth, maxWidth float64) (v map[int]Vehicle, err error)
+	// Find is a method that returns a map of vehicles that match every filter of the query
+	Find(ctx context.Context, q VehicleQuery) (v map[int]Vehicle, err error)
+	// FindPage is a method that returns the page of the vehicles that match every filter of the query, in its order
+	// - an empty page is not an error, Total tells whether any vehicle matched
+	FindPage(ctx context.Context, q VehicleQuery) (p VehiclePage, err error)
+	// Update is a method that replaces every attribute of a vehicle
+	Update(ctx context.Context, v *Vehicle) (err error)
+	// Patch is a method that applies a change to a vehicle and stores the result if it is valid
+	// - the change and the store are atomic, the id of the vehicle can not be changed
+	Patch(ctx context.Context, id int, patch func(v *Vehicle) (err error)) (v Vehicle, err error)
 }
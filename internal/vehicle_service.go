@@ -1,5 +1,7 @@
 package internal
 
+import "context"
+
 import "errors"
 
 var (
@@ -10,19 +12,46 @@ var (
 // VehicleService is an interface that represents a vehicle service
 type VehicleService interface {
 	// FindAll is a method that returns a map of all vehicles
-	FindAll() (v map[int]Vehicle, err error)
+	FindAll(ctx context.Context) (v map[int]Vehicle, err error)
+	// FindById is a method that returns a vehicle by id
+	FindById(ctx context.Context, id int) (v Vehicle, err error)
+	// FindByRegistration is a method that returns a vehicle by registration (case insensitive)
+	// - registrations are required and unique across vehicles
+	FindByRegistration(ctx context.Context, registration string) (v Vehicle, err error)
 	// Create is a method that adds a vehicle to the repository
-	Create(v *Vehicle) (err error)
+	Create(ctx context.Context, v *Vehicle) (err error)
 	// BatchCreate is a method that adds a list of vehicles to the repository
-	BatchCreate(v []*Vehicle) (err error)
+	BatchCreate(ctx context.Context, v []*Vehicle) (err error)
 	// FindByColorAndYear is a method that returns a map of vehicles that match color and year
-	FindByColorAndYear(color string, year int) (v map[int]Vehicle, err error)
+	FindByColorAndYear(ctx context.Context, color string, year int) (v map[int]Vehicle, err error)
 	// Delete is a method that deletes a vehicle from the repository
-	Delete(id int) (err error)
+	Delete(ctx context.Context, id int) (err error)
 	// UpdateFuelType is a method that updates the fuel type of a vehicle in the repository
-	UpdateFuelType(id int, fuelType string) (err error)
+	UpdateFuelType(ctx context.Context, id int, fuelType FuelType) (err error)
 	// FindByWeightRange is a method that returns a map of vehicles that match weight range
-	FindByWeightRange(minWeight, maxWeight float64) (v map[int]Vehicle, err error)
+	FindByWeightRange(ctx context.Context, minWeight, maxWeight float64) (v map[int]Vehicle, err error)
 	// FindByBrandAndYearRange is a method that returns a map of vehicles that match brand and year range
-	FindByBrandAndYearRange(brand string, minYear, maxYear int) (v map[int]Vehicle, err error)
+	FindByBrandAndYearRange(ctx context.Context, brand string, minYear, maxYear int) (v map[int]Vehicle, err error)
+	// FindAverageMaxSpeedByBrand is a method that returns the average max speed of the vehicles of a brand
+	FindAverageMaxSpeedByBrand(ctx context.Context, brand string) (avg float64, err error)
+	// UpdateMaxSpeed is a method that updates the max speed of a vehicle in the repository
+	UpdateMaxSpeed(ctx context.Context, id int, maxSpeed float64) (err error)
+	// FindByFuelType is a method that returns a map of vehicles that match fuel type
+	FindByFuelType(ctx context.Context, fuelType string) (v map[int]Vehicle, err error)
+	// FindByTransmission is a method that returns a map of vehicles that match transmission
+	FindByTransmission(ctx context.Context, transmission string) (v map[int]Vehicle, err error)
+	// FindAverageCapacityByBrand is a method that returns the average capacity of the vehicles of a brand
+	FindAverageCapacityByBrand(ctx context.Context, brand string) (avg float64, err error)
+	// FindByDimensions is a method that returns a map of vehicles that match length and width ranges
+	FindByDimensions(ctx context.Context, minLength, maxLength, minWidth, maxWidth float64) (v map[int]Vehicle, err error)
+	// Find is a method that returns a map of vehicles that match every filter of the query
+	Find(ctx context.Context, q VehicleQuery) (v map[int]Vehicle, err error)
+	// FindPage is a method that returns the page of the vehicles that match every filter of the query, in its order
+	// - an empty page is not an error, Total tells whether any vehicle matched
+	FindPage(ctx context.Context, q VehicleQuery) (p VehiclePage, err error)
+	// Update is a method that replaces every attribute of a vehicle
+	Update(ctx context.Context, v *Vehicle) (err error)
+	// Patch is a method that applies a change to a vehicle and stores the result if it is valid
+	// - the change and the store are atomic, the id of the vehicle can not be changed
+	Patch(ctx context.Context, id int, patch func(v *Vehicle) (err error)) (v Vehicle, err error)
 }
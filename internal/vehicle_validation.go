@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RuleRequired is the rule of the fields that can not be empty
+	RuleRequired = "required"
+	// RulePositive is the rule of the numeric fields that must be greater than 0
+	RulePositive = "positive"
+	// RuleMin is the rule of the numeric fields that must be greater than or equal to Limit
+	RuleMin = "min"
+	// RuleMax is the rule of the numeric fields that must be lower than or equal to Limit
+	RuleMax = "max"
+	// RuleOneOf is the rule of the text fields that must be one of Allowed
+	RuleOneOf = "one_of"
+)
+
+var (
+	// ErrVehicleInvalid is an error that represents that some attribute of the vehicle breaks a validation rule
+	ErrVehicleInvalid = errors.New("vehicle invalid")
+)
+
+// VehicleFieldError is a struct that represents a validation rule broken by a field of a vehicle
+type VehicleFieldError struct {
+	// Field is the JSON name of the field, a key of VehicleFields
+	Field string
+	// Rule is the broken rule (RuleRequired, RulePositive, RuleMin, RuleMax or RuleOneOf)
+	Rule string
+	// Limit is the bound of RuleMin and RuleMax
+	Limit float64
+	// Allowed is the list of values accepted by RuleOneOf
+	Allowed []string
+}
+
+// VehicleValidationError is an error that represents every validation rule broken by a vehicle
+type VehicleValidationError struct {
+	// Fields is the list of broken rules, in the order of the fields of the vehicle
+	Fields []VehicleFieldError
+}
+
+// Error is a method that returns the error message
+func (e *VehicleValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = fmt.Sprintf("%s (%s)", f.Field, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s", ErrVehicleInvalid, strings.Join(fields, ", "))
+}
+
+// Unwrap is a method that returns the reasons of the error
+// - ErrVehicleMandatoryFields if a field is missing, ErrVehicleInvalidMaxSpeed if the max speed is out of range,
+// ErrVehicleInvalidFuelType and ErrVehicleInvalidTransmission if those fields are not canonical,
+// so that callers checking those errors keep working
+func (e *VehicleValidationError) Unwrap() []error {
+	errs := []error{ErrVehicleInvalid}
+	for _, f := range e.Fields {
+		switch {
+		case f.Rule == RuleRequired:
+			errs = append(errs, ErrVehicleMandatoryFields)
+		case f.Field == "max_speed":
+			errs = append(errs, ErrVehicleInvalidMaxSpeed)
+		case f.Field == "fuel_type":
+			errs = append(errs, ErrVehicleInvalidFuelType)
+		case f.Field == "transmission":
+			errs = append(errs, ErrVehicleInvalidTransmission)
+		}
+	}
+	return errs
+}